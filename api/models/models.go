@@ -6,7 +6,10 @@ type TagInput struct {
 }
 
 type RegistryArtifactInput struct {
-	URL string `json:"url"`
+	URL       string `json:"url"`
+	Digest    string `json:"digest,omitempty"`
+	Platform  string `json:"platform,omitempty"`
+	MediaType string `json:"mediaType,omitempty"`
 }
 
 type AddArtifactInput struct {
@@ -15,4 +18,8 @@ type AddArtifactInput struct {
 	Version    string                `json:"version"`
 	Tags       []TagInput            `json:"tags"`
 	Registry   RegistryArtifactInput `json:"registry"`
+	// IdempotencyKey lets the server dedupe retried/re-run addArtifact
+	// calls for the same pushed image instead of creating a duplicate
+	// artifact.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
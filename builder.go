@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// BuildOptions configures a single Builder.Build invocation. When Platforms
+// has more than one entry, the build produces an OCI image index and pushes
+// it as a manifest list instead of a single-platform image.
+type BuildOptions struct {
+	Platforms  []string
+	BuildArgs  map[string]string
+	Target     string
+	CacheFrom  []string
+	CacheTo    []string
+	Labels     map[string]string
+	NoCache    bool
+	Secrets    map[string]string
+	Provenance bool
+	SBOM       bool
+}
+
+// needsBuildx reports whether opts uses any feature that only buildx
+// supports, meaning the build must go through Builder.Build instead of the
+// plain Engine API path in runDockerBuild.
+func (o BuildOptions) needsBuildx() bool {
+	return len(o.Platforms) > 0 || o.Target != "" || len(o.BuildArgs) > 0 ||
+		len(o.CacheFrom) > 0 || len(o.CacheTo) > 0 || len(o.Labels) > 0 || o.NoCache
+}
+
+// PlatformDigest records the content-addressed manifest digest produced for
+// one platform of a (possibly multi-arch) build.
+type PlatformDigest struct {
+	Platform  string
+	Digest    string
+	MediaType string
+}
+
+// BuildResult is the outcome of a Builder.Build call.
+type BuildResult struct {
+	// Digest is the manifest list digest for a multi-platform build, or the
+	// single image digest when only one platform was built.
+	Digest    string
+	MediaType string
+	Platforms []PlatformDigest
+}
+
+// Builder drives `docker buildx build`. Unlike the plain Engine API
+// ImageBuild call used by runDockerBuild, buildx is BuildKit-backed and is
+// the only path that can produce a multi-arch manifest list in one
+// invocation, so multi-platform builds are routed through here instead. It
+// shells out to the `docker` CLI rather than the Engine API client, since
+// buildx has no Engine API equivalent; auth comes from DOCKER_CONFIG, which
+// setupDockerAuth already points at the action's credential helper config.
+type Builder struct{}
+
+// NewBuilder creates a Builder.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// buildxMetadata mirrors the subset of `docker buildx build --metadata-file`
+// output this action needs.
+type buildxMetadata struct {
+	ImageDigest     string `json:"containerimage.digest"`
+	ImageDescriptor struct {
+		MediaType string `json:"mediaType"`
+	} `json:"containerimage.descriptor"`
+}
+
+// ociIndex is the subset of an OCI image index this action reads to recover
+// per-platform manifest digests after a multi-arch push.
+type ociIndex struct {
+	Manifests []struct {
+		Digest    string `json:"digest"`
+		MediaType string `json:"mediaType"`
+		Platform  struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// Build runs `docker buildx build` with the given options, pushing the
+// result to the registry, and returns the manifest digest(s) produced.
+func (b *Builder) Build(dockerfilePath, imageName, imageTag string, opts BuildOptions) (*BuildResult, error) {
+	log.Println("Building Docker image with buildx...")
+
+	fullImageName := fmt.Sprintf("%s/%s:%s", registryURL, imageName, imageTag)
+
+	metadataFile, err := os.CreateTemp("", "buildx-metadata-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create buildx metadata file: %w", err)
+	}
+	metadataPath := metadataFile.Name()
+	metadataFile.Close()
+	defer os.Remove(metadataPath)
+
+	args := []string{
+		"buildx", "build",
+		"-f", dockerfilePath,
+		"-t", fullImageName,
+		"--metadata-file", metadataPath,
+		"--push",
+	}
+
+	if len(opts.Platforms) > 0 {
+		args = append(args, "--platform", strings.Join(opts.Platforms, ","))
+	}
+	if opts.Target != "" {
+		args = append(args, "--target", opts.Target)
+	}
+	if opts.NoCache {
+		args = append(args, "--no-cache")
+	}
+	if opts.Provenance {
+		args = append(args, "--provenance=true")
+	}
+	if opts.SBOM {
+		args = append(args, "--sbom=true")
+	}
+	for k, v := range opts.BuildArgs {
+		args = append(args, "--build-arg", fmt.Sprintf("%s=%s", k, v))
+	}
+	for k, v := range opts.Labels {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", k, v))
+	}
+	for id, env := range opts.Secrets {
+		args = append(args, "--secret", fmt.Sprintf("id=%s,env=%s", id, env))
+	}
+	for _, c := range opts.CacheFrom {
+		args = append(args, "--cache-from", c)
+	}
+	for _, c := range opts.CacheTo {
+		args = append(args, "--cache-to", c)
+	}
+	args = append(args, ".")
+
+	cmd := exec.Command("docker", args...)
+	events := newDockerEventWriter(fmt.Sprintf("Building %s", fullImageName))
+	cmd.Stdout = events
+	cmd.Stderr = events
+	runErr := cmd.Run()
+	closeErr := events.Close()
+	if runErr != nil {
+		return nil, fmt.Errorf("buildx build failed: %w", runErr)
+	}
+	if closeErr != nil {
+		return nil, fmt.Errorf("buildx build failed: %w", closeErr)
+	}
+
+	metadataBytes, err := os.ReadFile(metadataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read buildx metadata: %w", err)
+	}
+
+	var meta buildxMetadata
+	if err := json.Unmarshal(metadataBytes, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse buildx metadata: %w", err)
+	}
+
+	result := &BuildResult{
+		Digest:    meta.ImageDigest,
+		MediaType: meta.ImageDescriptor.MediaType,
+	}
+
+	switch {
+	case len(opts.Platforms) > 1:
+		platforms, err := resolvePlatformDigests(fullImageName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve per-platform digests: %w", err)
+		}
+		result.Platforms = platforms
+	case len(opts.Platforms) == 1:
+		result.Platforms = []PlatformDigest{{Platform: opts.Platforms[0], Digest: result.Digest, MediaType: result.MediaType}}
+	}
+
+	log.Println("Docker image built:", fullImageName, "digest:", result.Digest)
+	return result, nil
+}
+
+// resolvePlatformDigests inspects the pushed manifest list and returns the
+// digest of each platform-specific manifest it references.
+func resolvePlatformDigests(fullImageName string) ([]PlatformDigest, error) {
+	out, err := exec.Command("docker", "buildx", "imagetools", "inspect", "--raw", fullImageName).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var index ociIndex
+	if err := json.Unmarshal(out, &index); err != nil {
+		return nil, err
+	}
+
+	digests := make([]PlatformDigest, 0, len(index.Manifests))
+	for _, m := range index.Manifests {
+		digests = append(digests, PlatformDigest{
+			Platform:  m.Platform.OS + "/" + m.Platform.Architecture,
+			Digest:    m.Digest,
+			MediaType: m.MediaType,
+		})
+	}
+	return digests, nil
+}
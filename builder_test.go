@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dockertesting "github.com/fsouza/go-dockerclient/testing"
+)
+
+// TestRunDockerBuildAgainstFakeDaemon exercises runDockerBuild's Engine API
+// path end-to-end against the fake daemon this dependency ships for exactly
+// this purpose, rather than asserting on a captured docker CLI stdout blob.
+func TestRunDockerBuildAgainstFakeDaemon(t *testing.T) {
+	server, err := dockertesting.NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatalf("failed to start fake docker daemon: %v", err)
+	}
+	defer server.Stop()
+
+	addr := strings.TrimSuffix(strings.TrimPrefix(server.URL(), "http://"), "/")
+	t.Setenv("DOCKER_HOST", "tcp://"+addr)
+
+	client, err := newDockerClient()
+	if err != nil {
+		t.Fatalf("newDockerClient: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatalf("failed to write Dockerfile fixture: %v", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("os.Getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("os.Chdir: %v", err)
+	}
+	defer os.Chdir(cwd)
+
+	if err := runDockerBuild(client, "Dockerfile", "", "test-image", "latest"); err != nil {
+		t.Fatalf("runDockerBuild returned an error against the fake daemon: %v", err)
+	}
+}
@@ -3,8 +3,11 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -14,6 +17,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	docker "github.com/fsouza/go-dockerclient"
 	"github.com/hasura/go-graphql-client"
 	"github.com/trynova-ai/build-and-push-action/api/models"
 )
@@ -86,41 +90,228 @@ func getBearerToken(clientId, secret string) (string, string, error) {
 	return authResp.AccessToken, organizationId, nil
 }
 
-func updateDockerConfig(token string) error {
-	log.Println("Updating Docker config...")
+// dockerConfigAuth mirrors one entry of the "auths" map in Docker's
+// config.json, matching the AuthConfig schema the daemon/CLI expect.
+type dockerConfigAuth struct {
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// dockerConfigFile is the subset of Docker's config.json this action
+// writes: per-registry auth plus an optional credential helper.
+type dockerConfigFile struct {
+	Auths       map[string]dockerConfigAuth `json:"auths,omitempty"`
+	CredsStore  string                      `json:"credsStore,omitempty"`
+	CredHelpers map[string]string           `json:"credHelpers,omitempty"`
+}
+
+// setupDockerAuth prepares the registry credentials for this run. It writes
+// a per-run DOCKER_CONFIG directory (the caller is responsible for pointing
+// child docker/buildx processes at it and removing it afterwards) rather
+// than touching the runner's shared ~/.docker/config.json, and returns the
+// matching AuthConfiguration for pushing directly through the Engine API
+// client. When DOCKER_CRED_HELPER is set, the token is never written to
+// disk at all: the helper is registered in credHelpers and queried directly
+// via its docker-credential-<name> "get" protocol.
+func setupDockerAuth(token string) (configDir string, auth docker.AuthConfiguration, err error) {
+	log.Println("Configuring Docker registry auth...")
+
+	configDir, err = os.MkdirTemp("", "docker-config-")
+	if err != nil {
+		return "", docker.AuthConfiguration{}, err
+	}
+
+	if credHelper := os.Getenv("DOCKER_CRED_HELPER"); credHelper != "" {
+		cfg := dockerConfigFile{CredHelpers: map[string]string{registryURL: credHelper}}
+		if err := writeDockerConfig(configDir, cfg); err != nil {
+			return "", docker.AuthConfiguration{}, err
+		}
 
-	configContent := fmt.Sprintf(`{
-		"HttpHeaders" : {
-			"X-Meta-Authorization" : "Bearer %s"
+		auth, err := credHelperAuth(credHelper, registryURL)
+		if err != nil {
+			return "", docker.AuthConfiguration{}, fmt.Errorf("failed to read credentials from %s: %w", credHelper, err)
 		}
-	}`, token)
 
-	homeDir, err := os.UserHomeDir()
+		log.Println("Docker auth delegated to credential helper:", credHelper)
+		return configDir, auth, nil
+	}
+
+	cfg := dockerConfigFile{
+		Auths: map[string]dockerConfigAuth{
+			registryURL: {IdentityToken: token},
+		},
+	}
+	if err := writeDockerConfig(configDir, cfg); err != nil {
+		return "", docker.AuthConfiguration{}, err
+	}
+
+	log.Println("Docker config written to", configDir)
+	return configDir, docker.AuthConfiguration{ServerAddress: registryURL, IdentityToken: token}, nil
+}
+
+func writeDockerConfig(dir string, cfg dockerConfigFile) error {
+	b, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return err
 	}
+	return os.WriteFile(filepath.Join(dir, "config.json"), b, 0600)
+}
 
-	dockerConfigPath := filepath.Join(homeDir, ".docker")
-	if err := os.MkdirAll(dockerConfigPath, 0700); err != nil {
-		return err
+// credHelperAuth resolves credentials for serverURL through a native Docker
+// credential helper binary, speaking its stdin/stdout "get" protocol, so the
+// token is never persisted to disk.
+func credHelperAuth(helper, serverURL string) (docker.AuthConfiguration, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return docker.AuthConfiguration{}, err
 	}
 
-	configFile := filepath.Join(dockerConfigPath, "config.json")
-	if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
-		return err
+	var resp struct {
+		ServerURL string `json:"ServerURL"`
+		Username  string `json:"Username"`
+		Secret    string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return docker.AuthConfiguration{}, err
 	}
 
-	log.Println("Docker config updated.")
-	return nil
+	return docker.AuthConfiguration{
+		ServerAddress: serverURL,
+		Username:      resp.Username,
+		Password:      resp.Secret,
+	}, nil
+}
+
+// newDockerClient builds an Engine API client from the environment, honoring
+// DOCKER_HOST, DOCKER_TLS_VERIFY and DOCKER_CERT_PATH the same way the docker
+// CLI does.
+func newDockerClient() (*docker.Client, error) {
+	client, err := docker.NewClientFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return client, nil
+}
+
+// dockerEvent mirrors a single line of the JSON event stream returned by
+// ImageBuild/ImagePush on the Engine API.
+type dockerEvent struct {
+	Stream         string `json:"stream"`
+	Status         string `json:"status"`
+	ID             string `json:"id"`
+	Progress       string `json:"progress"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error       string `json:"error"`
+	ErrorDetail *struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+	// Aux carries the pushed manifest digest on the final push event.
+	Aux *struct {
+		Tag    string `json:"Tag"`
+		Digest string `json:"Digest"`
+		Size   int64  `json:"Size"`
+	} `json:"aux"`
+}
+
+// dockerEventWriter implements io.Writer and renders a raw docker JSON event
+// stream as GitHub Actions log groups, one per layer/step, while buffering
+// any errorDetail so it can be surfaced as a typed Go error.
+type dockerEventWriter struct {
+	group   string
+	buf     bytes.Buffer
+	inGroup bool
+	err     error
+	// digest is populated from the push event stream's aux.Digest, if any.
+	digest string
+}
+
+func newDockerEventWriter(group string) *dockerEventWriter {
+	return &dockerEventWriter{group: group}
+}
+
+func (w *dockerEventWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			// No newline yet; put the partial line back for the next Write.
+			w.buf.Reset()
+			w.buf.Write(line)
+			break
+		}
+		w.handleLine(line)
+	}
+
+	return len(p), nil
+}
+
+func (w *dockerEventWriter) handleLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+
+	var ev dockerEvent
+	if err := json.Unmarshal(line, &ev); err != nil {
+		fmt.Println(string(line))
+		return
+	}
+
+	if ev.ErrorDetail != nil {
+		w.err = fmt.Errorf("%s", ev.ErrorDetail.Message)
+		return
+	}
+	if ev.Error != "" {
+		w.err = fmt.Errorf("%s", ev.Error)
+		return
+	}
+	if ev.Aux != nil && ev.Aux.Digest != "" {
+		w.digest = ev.Aux.Digest
+	}
+
+	if !w.inGroup {
+		fmt.Printf("::group::%s\n", w.group)
+		w.inGroup = true
+	}
+
+	switch {
+	case ev.Stream != "":
+		fmt.Print(ev.Stream)
+	case ev.ID != "" && ev.Status != "":
+		if ev.ProgressDetail.Total > 0 {
+			fmt.Printf("%s: %s (%d/%d)\n", ev.ID, ev.Status, ev.ProgressDetail.Current, ev.ProgressDetail.Total)
+		} else {
+			fmt.Printf("%s: %s\n", ev.ID, ev.Status)
+		}
+	case ev.Status != "":
+		fmt.Println(ev.Status)
+	}
+}
+
+// Close flushes any buffered partial line and closes the log group.
+func (w *dockerEventWriter) Close() error {
+	if w.buf.Len() > 0 {
+		w.handleLine(w.buf.Bytes())
+		w.buf.Reset()
+	}
+	if w.inGroup {
+		fmt.Println("::endgroup::")
+	}
+	return w.err
 }
 
-func runDockerBuild(dockerfilePath, dockerfile, imageName, imageTag string) error {
+func runDockerBuild(client *docker.Client, dockerfilePath, dockerfile, imageName, imageTag string) error {
 	log.Println("Building Docker image...")
 
 	fullImageName := fmt.Sprintf("%s/%s:%s", registryURL, imageName, imageTag)
 
-	var cmd *exec.Cmd
-
 	if dockerfile != "" {
 		dockerfilePath = "./Dockerfile"
 		if err := os.WriteFile(dockerfilePath, []byte(dockerfile), 0644); err != nil {
@@ -128,37 +319,94 @@ func runDockerBuild(dockerfilePath, dockerfile, imageName, imageTag string) erro
 		}
 	}
 
-	if dockerfilePath != "" {
-		cmd = exec.Command("docker", "build", "-f", dockerfilePath, "-t", fullImageName, ".")
-	} else {
+	if dockerfilePath == "" {
 		return fmt.Errorf("either dockerfilePath or dockerfile must be set")
 	}
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return err
+	events := newDockerEventWriter(fmt.Sprintf("Building %s", fullImageName))
+	err := client.BuildImage(docker.BuildImageOptions{
+		Name:          fullImageName,
+		Dockerfile:    dockerfilePath,
+		ContextDir:    ".",
+		OutputStream:  events,
+		RawJSONStream: true,
+	})
+	if closeErr := events.Close(); closeErr != nil {
+		return fmt.Errorf("docker build failed: %w", closeErr)
+	}
+	if err != nil {
+		return fmt.Errorf("docker build failed: %w", err)
 	}
 
 	log.Println("Docker image built:", fullImageName)
 	return nil
 }
 
-func runDockerPush(imageName, imageTag string) (string, error) {
+// runDockerPush pushes imageName:imageTag and returns its manifest digest,
+// so callers get a content-addressed reference instead of just the mutable
+// tag.
+func runDockerPush(client *docker.Client, imageName, imageTag string, auth docker.AuthConfiguration) (string, error) {
 	log.Println("Pushing Docker image...")
 
 	fullImageName := fmt.Sprintf("%s/%s:%s", registryURL, imageName, imageTag)
 
-	cmd := exec.Command("docker", "push", fullImageName)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
-	if err := cmd.Run(); err != nil {
-		return out.String(), err
+	events := newDockerEventWriter(fmt.Sprintf("Pushing %s", fullImageName))
+	err := client.PushImage(docker.PushImageOptions{
+		Name:          fmt.Sprintf("%s/%s", registryURL, imageName),
+		Tag:           imageTag,
+		OutputStream:  events,
+		RawJSONStream: true,
+	}, auth)
+	if closeErr := events.Close(); closeErr != nil {
+		return "", fmt.Errorf("docker push failed: %w", closeErr)
+	}
+	if err != nil {
+		return "", fmt.Errorf("docker push failed: %w", err)
+	}
+
+	digest := events.digest
+	if digest == "" {
+		digest, err = resolveManifestDigest(imageName, imageTag, auth)
+		if err != nil {
+			return "", fmt.Errorf("docker push succeeded but failed to resolve manifest digest: %w", err)
+		}
+	}
+
+	log.Println("Docker image pushed:", fullImageName, "digest:", digest)
+	return digest, nil
+}
+
+// resolveManifestDigest falls back to a HEAD request against the registry's
+// v2 manifests endpoint when the push event stream didn't report aux.Digest.
+func resolveManifestDigest(imageName, imageTag string, auth docker.AuthConfiguration) (string, error) {
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", registryURL, imageName, imageTag)
+
+	req, err := http.NewRequest(http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.oci.image.manifest.v1+json, application/vnd.docker.distribution.manifest.v2+json")
+	if auth.Username != "" {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	} else if auth.IdentityToken != "" {
+		req.Header.Set("Authorization", "Bearer "+auth.IdentityToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
 	}
+	defer resp.Body.Close()
 
-	log.Println("Docker image pushed:", fullImageName)
-	return out.String(), nil
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status from registry: %s", resp.Status)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry response did not include a Docker-Content-Digest header")
+	}
+	return digest, nil
 }
 
 func parseLocation(imageName, imageTag string) string {
@@ -187,7 +435,26 @@ func (t *customTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return t.Transport.RoundTrip(req)
 }
 
-func addArtifact(token, organization, artifactId, version, url string) error {
+// platformTags renders per-platform manifest digests as TagInput entries so
+// a multi-arch artifact's constituent images are still visible on the
+// single addArtifact record.
+func platformTags(platforms []PlatformDigest) []models.TagInput {
+	tags := make([]models.TagInput, 0, len(platforms))
+	for _, p := range platforms {
+		tags = append(tags, models.TagInput{Key: "platform:" + p.Platform, Value: p.Digest})
+	}
+	return tags
+}
+
+// artifactIdempotencyKey derives a stable key for one pushed image so a
+// re-run after a crash (or a retried mutation that actually succeeded
+// server-side) doesn't create a duplicate artifact.
+func artifactIdempotencyKey(imageName, imageTag, digest string) string {
+	sum := sha256.Sum256([]byte(imageName + imageTag + digest))
+	return hex.EncodeToString(sum[:])
+}
+
+func addArtifact(token, organization, artifactId, imageName, imageTag, url string, build *BuildResult, extraTags []models.TagInput) error {
 	log.Println("Adding artifact to GraphQL API...")
 
 	httpClient := &http.Client{
@@ -198,7 +465,11 @@ func addArtifact(token, organization, artifactId, version, url string) error {
 		},
 	}
 
-	client := graphql.NewClient(apiURL, httpClient)
+	client := graphql.NewClient(apiURL, httpClient).
+		WithRetry(graphql.DefaultRetryPolicy()).
+		WithMiddleware(func(ctx context.Context, info graphql.RequestInfo) {
+			log.Printf("GraphQL %s took %s (err=%v)", info.Operation, info.Duration, info.Err)
+		})
 
 	var mutation struct {
 		AddArtifact struct {
@@ -206,12 +477,29 @@ func addArtifact(token, organization, artifactId, version, url string) error {
 		} `graphql:"addArtifact(input: $input)"`
 	}
 
+	registry := models.RegistryArtifactInput{URL: url}
+	tags := []models.TagInput{}
+	if build != nil {
+		registry.Digest = build.Digest
+		registry.MediaType = build.MediaType
+		// A single-platform build's digest already identifies one platform,
+		// so the registry record can name it directly. A multi-platform
+		// build's digest is the manifest-list index instead, which has no
+		// single platform; per-platform digests stay in tags.
+		if len(build.Platforms) == 1 {
+			registry.Platform = build.Platforms[0].Platform
+		}
+		tags = platformTags(build.Platforms)
+	}
+	tags = append(tags, extraTags...)
+
 	input := models.AddArtifactInput{
-		Type:       "registry",
-		ArtifactID: artifactId,
-		Version:    version,
-		Tags:       []models.TagInput{},
-		Registry:   models.RegistryArtifactInput{URL: url},
+		Type:           "registry",
+		ArtifactID:     artifactId,
+		Version:        imageTag,
+		Tags:           tags,
+		Registry:       registry,
+		IdempotencyKey: artifactIdempotencyKey(imageName, imageTag, registry.Digest),
 	}
 
 	variables := map[string]interface{}{
@@ -228,14 +516,55 @@ func addArtifact(token, organization, artifactId, version, url string) error {
 
 }
 
+// splitCSV splits a comma-separated flag value, dropping empty entries.
+func splitCSV(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// parseKeyValueList parses a comma-separated KEY=VALUE list, as used by the
+// build-args and labels flags.
+func parseKeyValueList(s string) map[string]string {
+	pairs := splitCSV(s)
+	if len(pairs) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		k, v, _ := strings.Cut(pair, "=")
+		out[k] = v
+	}
+	return out
+}
+
 func main() {
 	log.Println("Starting Docker Push Action...")
 
-	if len(os.Args) != 7 {
-		log.Fatalf("Usage: %s <clientId> <secret> <imageName> <imageTag> <artifactId> <dockerfilePath> <dockerfile>", os.Args[0])
+	platforms := flag.String("platforms", "", "comma-separated target platforms for a multi-arch build (e.g. linux/amd64,linux/arm64)")
+	buildArgs := flag.String("build-args", "", "comma-separated KEY=VALUE build arguments")
+	target := flag.String("target", "", "Dockerfile build stage to target")
+	cacheFrom := flag.String("cache-from", "", "comma-separated buildx cache-from sources")
+	cacheTo := flag.String("cache-to", "", "comma-separated buildx cache-to destinations")
+	labels := flag.String("labels", "", "comma-separated KEY=VALUE OCI image labels")
+	noCache := flag.Bool("no-cache", false, "disable the build cache")
+	sign := flag.Bool("sign", false, "sign the pushed image with cosign and attach a SLSA provenance attestation")
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) != 7 {
+		log.Fatalf("Usage: %s [flags] <clientId> <secret> <imageName> <imageTag> <artifactId> <dockerfilePath> <dockerfile>", os.Args[0])
 	}
 
-	clientId, secret, imageName, imageTag, artifactId, dockerfilePath, dockerfile := os.Args[1], os.Args[2], os.Args[3], os.Args[4], os.Args[5], os.Args[6], os.Args[7]
+	clientId, secret, imageName, imageTag, artifactId, dockerfilePath, dockerfile := args[0], args[1], args[2], args[3], args[4], args[5], args[6]
 
 	log.Printf("ClientId: %s, ImageName: %s, ImageTag: %s", clientId, imageName, imageTag)
 
@@ -244,19 +573,61 @@ func main() {
 		log.Fatalf("Failed to get bearer token: %v", err)
 	}
 
-	err = updateDockerConfig(token)
+	dockerConfigDir, registryAuth, err := setupDockerAuth(token)
 	if err != nil {
-		log.Fatalf("Failed to update Docker config: %v", err)
+		log.Fatalf("Failed to configure Docker auth: %v", err)
 	}
+	defer os.RemoveAll(dockerConfigDir)
+	os.Setenv("DOCKER_CONFIG", dockerConfigDir)
 
-	err = runDockerBuild(dockerfilePath, dockerfile, imageName, imageTag)
+	dockerClient, err := newDockerClient()
 	if err != nil {
-		log.Fatalf("Failed to build Docker image: %v", err)
+		log.Fatalf("Failed to create docker client: %v", err)
 	}
 
-	output, err := runDockerPush(imageName, imageTag)
-	if err != nil {
-		log.Fatalf("Failed to push Docker image: %v\nOutput: %s", err, output)
+	buildOpts := BuildOptions{
+		Platforms: splitCSV(*platforms),
+		BuildArgs: parseKeyValueList(*buildArgs),
+		Target:    *target,
+		CacheFrom: splitCSV(*cacheFrom),
+		CacheTo:   splitCSV(*cacheTo),
+		Labels:    parseKeyValueList(*labels),
+		NoCache:   *noCache,
+	}
+
+	var buildResult *BuildResult
+	if buildOpts.needsBuildx() {
+		// A multi-arch build, or one that needs any buildx-only feature
+		// (build args, cache import/export, labels, no-cache, target),
+		// pushes atomically as part of the build step.
+		builder := NewBuilder()
+		buildResult, err = builder.Build(dockerfilePath, imageName, imageTag, buildOpts)
+		if err != nil {
+			log.Fatalf("Failed to build Docker image: %v", err)
+		}
+	} else {
+		err = runDockerBuild(dockerClient, dockerfilePath, dockerfile, imageName, imageTag)
+		if err != nil {
+			log.Fatalf("Failed to build Docker image: %v", err)
+		}
+
+		digest, err := runDockerPush(dockerClient, imageName, imageTag, registryAuth)
+		if err != nil {
+			log.Fatalf("Failed to push Docker image: %v", err)
+		}
+		buildResult = &BuildResult{Digest: digest}
+	}
+
+	var signatureTags []models.TagInput
+	if *sign {
+		sigTag, attTag, err := signImage(imageName, imageTag, buildResult.Digest)
+		if err != nil {
+			log.Fatalf("Failed to sign image: %v", err)
+		}
+		signatureTags = []models.TagInput{
+			{Key: "signature", Value: sigTag},
+			{Key: "attestation", Value: attTag},
+		}
 	}
 
 	location := parseLocation(imageName, imageTag)
@@ -265,7 +636,7 @@ func main() {
 		log.Fatalf("Failed to set output: %v", err)
 	}
 
-	err = addArtifact(token, org, artifactId, imageTag, location)
+	err = addArtifact(token, org, artifactId, imageName, imageTag, location, buildResult, signatureTags)
 	if err != nil {
 		log.Fatalf("Failed to add artifact: %v", err)
 	}
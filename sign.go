@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// inTotoStatement is the generic in-toto v1 statement envelope. Predicate
+// carries the SLSA provenance payload cosign attaches to the image.
+type inTotoStatement struct {
+	Type          string          `json:"_type"`
+	PredicateType string          `json:"predicateType"`
+	Subject       []inTotoSubject `json:"subject"`
+	Predicate     any             `json:"predicate"`
+}
+
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// signImage signs the pushed image with cosign and attaches a SLSA
+// provenance attestation, pushing both as sibling artifacts to the registry
+// under the sha256-<digest>.sig / .att tags cosign uses. It returns those
+// tag names so they can be recorded on the addArtifact mutation.
+func signImage(imageName, imageTag, digest string) (sigTag, attTag string, err error) {
+	if digest == "" {
+		return "", "", fmt.Errorf("cannot sign: no image digest available")
+	}
+
+	shortDigest := strings.TrimPrefix(digest, "sha256:")
+	sigTag = fmt.Sprintf("sha256-%s.sig", shortDigest)
+	attTag = fmt.Sprintf("sha256-%s.att", shortDigest)
+
+	ref := fmt.Sprintf("%s/%s@%s", registryURL, imageName, digest)
+
+	log.Println("Signing image:", ref)
+	signCmd := exec.Command("cosign", "sign", "--yes", ref)
+	signCmd.Stdout = os.Stdout
+	signCmd.Stderr = os.Stderr
+	if err := signCmd.Run(); err != nil {
+		return "", "", fmt.Errorf("cosign sign failed: %w", err)
+	}
+
+	provenance, err := buildSLSAProvenance(fmt.Sprintf("%s/%s", registryURL, imageName), digest)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build SLSA provenance: %w", err)
+	}
+
+	predicateFile, err := os.CreateTemp("", "slsa-provenance-*.json")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(predicateFile.Name())
+
+	if _, err := predicateFile.Write(provenance); err != nil {
+		predicateFile.Close()
+		return "", "", err
+	}
+	if err := predicateFile.Close(); err != nil {
+		return "", "", err
+	}
+
+	attestCmd := exec.Command("cosign", "attest", "--yes",
+		"--predicate", predicateFile.Name(),
+		"--type", "slsaprovenance",
+		ref)
+	attestCmd.Stdout = os.Stdout
+	attestCmd.Stderr = os.Stderr
+	if err := attestCmd.Run(); err != nil {
+		return "", "", fmt.Errorf("cosign attest failed: %w", err)
+	}
+
+	log.Println("Image signed and attested:", ref)
+	return sigTag, attTag, nil
+}
+
+// buildSLSAProvenance renders the in-toto statement cosign attest expects as
+// its --predicate file.
+func buildSLSAProvenance(imageName, digest string) ([]byte, error) {
+	statement := inTotoStatement{
+		Type:          "https://in-toto.io/Statement/v1",
+		PredicateType: "https://slsa.dev/provenance/v1",
+		Subject: []inTotoSubject{
+			{Name: imageName, Digest: map[string]string{"sha256": strings.TrimPrefix(digest, "sha256:")}},
+		},
+		Predicate: map[string]any{
+			"buildType": "https://github.com/trynova-ai/build-and-push-action",
+			"builder": map[string]string{
+				"id": "https://github.com/trynova-ai/build-and-push-action",
+			},
+		},
+	}
+	return json.MarshalIndent(statement, "", "  ")
+}
@@ -0,0 +1,6 @@
+package homedir // import "github.com/docker/docker/pkg/homedir"
+
+const (
+	envKeyName   = "USERPROFILE"
+	homeShortCut = "%USERPROFILE%" // be careful while using in format functions
+)
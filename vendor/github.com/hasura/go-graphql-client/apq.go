@@ -0,0 +1,245 @@
+package graphql
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// defaultAPQCacheSize bounds the number of rendered-query -> hash entries
+// apqCache keeps, evicting least-recently-used once full.
+const defaultAPQCacheSize = 256
+
+// persistedQueryNotFoundMessage is the error message/code servers implementing
+// the Apollo APQ protocol return when they don't recognize a hash-only
+// request, signaling the client to retry with the full query attached.
+const (
+	persistedQueryNotFoundMessage = "PersistedQueryNotFound"
+	persistedQueryNotFoundCode    = "PERSISTED_QUERY_NOT_FOUND"
+)
+
+// apqExtension is the "extensions.persistedQuery" object both request
+// attempts carry.
+type apqExtension struct {
+	PersistedQuery apqPersistedQuery `json:"persistedQuery"`
+}
+
+type apqPersistedQuery struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+// apqRequestPayload is the POST body for an APQ request. Query is omitted
+// on the first (hash-only) attempt and included on the retry.
+type apqRequestPayload struct {
+	Query         string         `json:"query,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+	Extensions    apqExtension   `json:"extensions"`
+}
+
+// apqCache caches the sha256 hash computed for each distinct rendered query
+// string, so WithAutomaticPersistedQueries doesn't rehash on every call.
+// It evicts the least-recently-used entry once full.
+type apqCache struct {
+	cap int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type apqCacheEntry struct {
+	query string
+	hash  string
+}
+
+func newAPQCache(capacity int) *apqCache {
+	if capacity <= 0 {
+		capacity = defaultAPQCacheSize
+	}
+	return &apqCache{
+		cap:   capacity,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// hashFor returns the sha256 hex digest of query, computing and caching it
+// on first use.
+func (c *apqCache) hashFor(query string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[query]; ok {
+		c.ll.MoveToFront(el)
+		return el.Value.(*apqCacheEntry).hash
+	}
+
+	sum := sha256.Sum256([]byte(query))
+	hash := hex.EncodeToString(sum[:])
+
+	el := c.ll.PushFront(&apqCacheEntry{query: query, hash: hash})
+	c.items[query] = el
+
+	if c.ll.Len() > c.cap {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*apqCacheEntry).query)
+		}
+	}
+
+	return hash
+}
+
+// WithAutomaticPersistedQueries returns a copy of the client with the
+// Apollo Automatic Persisted Queries protocol enabled: each query is first
+// sent as just its sha256 hash (via GET when the client's HTTP method
+// allows it, POST otherwise), and only resent with the full query text if
+// the server responds with PersistedQueryNotFound.
+func (c *Client) WithAutomaticPersistedQueries() *Client {
+	return &Client{
+		url:             c.url,
+		httpClient:      c.httpClient,
+		requestModifier: c.requestModifier,
+		debug:           c.debug,
+		retryPolicy:     c.retryPolicy,
+		middlewares:     c.middlewares,
+		httpMethod:      c.httpMethod,
+		maxURLLength:    c.maxURLLength,
+		autoBatch:       c.autoBatch,
+		apqCache:        newAPQCache(defaultAPQCacheSize),
+		validation:      c.validation,
+	}
+}
+
+// requestAPQ implements the two-attempt APQ handshake around sendOnce. It
+// is only reached when c.apqCache is set.
+func (c *Client) requestAPQ(ctx context.Context, op operationType, query string, variables map[string]any, options *constructOptionsOutput) ([]byte, []byte, *http.Response, io.Reader, Errors) {
+	hash := c.apqCache.hashFor(query)
+
+	request, reqReader, buildErr := c.buildAPQRequest(ctx, op, "", hash, variables, options)
+	if buildErr != nil {
+		return nil, nil, nil, nil, Errors{*buildErr}
+	}
+	if c.requestModifier != nil {
+		c.requestModifier(request)
+	}
+
+	data, extData, resp, respBuf, errs := c.sendOnce(request, reqReader)
+	if !isPersistedQueryNotFound(errs) {
+		return data, extData, resp, respBuf, errs
+	}
+
+	request, reqReader, buildErr = c.buildAPQRequest(ctx, op, query, hash, variables, options)
+	if buildErr != nil {
+		return nil, nil, nil, nil, Errors{*buildErr}
+	}
+	if c.requestModifier != nil {
+		c.requestModifier(request)
+	}
+
+	return c.sendOnce(request, reqReader)
+}
+
+// isPersistedQueryNotFound reports whether errs is the server telling us it
+// doesn't (yet, or any longer) recognize the persisted query hash.
+func isPersistedQueryNotFound(errs Errors) bool {
+	for _, e := range errs {
+		if e.Message == persistedQueryNotFoundMessage {
+			return true
+		}
+		if code, ok := e.Extensions["code"].(string); ok && code == persistedQueryNotFoundCode {
+			return true
+		}
+	}
+	return false
+}
+
+// buildAPQRequest renders one APQ attempt: query == "" means the hash-only
+// attempt, non-empty means the retry that includes the full query text
+// alongside the same hash. Like buildHTTPRequest, it uses GET when allowed
+// and the resulting URL fits the configured max length, POST otherwise.
+func (c *Client) buildAPQRequest(ctx context.Context, op operationType, query string, hash string, variables map[string]any, options *constructOptionsOutput) (*http.Request, *bytes.Reader, *Error) {
+	ext := apqExtension{PersistedQuery: apqPersistedQuery{Version: 1, Sha256Hash: hash}}
+
+	method := c.httpMethod
+	if options != nil && options.httpMethod != "" {
+		method = options.httpMethod
+	}
+
+	if method == http.MethodGet && op != mutationOperation {
+		req, ok, err := c.buildAPQGetRequest(ctx, query, ext, variables, options)
+		if err != nil {
+			e := newError(ErrRequestError, fmt.Errorf("problem constructing APQ GET request: %w", err))
+			return nil, nil, &e
+		}
+		if ok {
+			return req, bytes.NewReader(nil), nil
+		}
+		// Falls through to POST: the GET URL would exceed maxURLLength.
+	}
+
+	in := apqRequestPayload{Query: query, Variables: variables, Extensions: ext}
+	if options != nil {
+		in.OperationName = options.operationName
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(in); err != nil {
+		e := newError(ErrGraphQLEncode, err)
+		return nil, nil, &e
+	}
+
+	reqReader := bytes.NewReader(buf.Bytes())
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, reqReader)
+	if err != nil {
+		e := newError(ErrRequestError, fmt.Errorf("problem constructing request: %w", err))
+		return nil, nil, &e
+	}
+	request.Header.Add("Content-Type", "application/json")
+
+	return request, reqReader, nil
+}
+
+func (c *Client) buildAPQGetRequest(ctx context.Context, query string, ext apqExtension, variables map[string]any, options *constructOptionsOutput) (req *http.Request, ok bool, err error) {
+	q := url.Values{}
+	if query != "" {
+		q.Set("query", query)
+	}
+	if options != nil && options.operationName != "" {
+		q.Set("operationName", options.operationName)
+	}
+	if len(variables) > 0 {
+		varsJSON, err := json.Marshal(variables)
+		if err != nil {
+			return nil, false, err
+		}
+		q.Set("variables", string(varsJSON))
+	}
+	extJSON, err := json.Marshal(ext)
+	if err != nil {
+		return nil, false, err
+	}
+	q.Set("extensions", string(extJSON))
+
+	fullURL := c.url + "?" + q.Encode()
+	if len(fullURL) > c.effectiveMaxURLLength() {
+		return nil, false, nil
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	return req, true, nil
+}
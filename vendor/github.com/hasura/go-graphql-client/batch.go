@@ -0,0 +1,281 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hasura/go-graphql-client/pkg/jsonutil"
+)
+
+// batchItem is the wire shape of one entry in a batched request/response
+// array, matching the convention used by Apollo Server / express-graphql.
+type batchItem struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+}
+
+type batchItemResponse struct {
+	Data       json.RawMessage `json:"data"`
+	Extensions json.RawMessage `json:"extensions,omitempty"`
+	Errors     Errors          `json:"errors,omitempty"`
+}
+
+// batchOperation is one queued Query/Mutate call awaiting Batch.Do.
+type batchOperation struct {
+	op            operationType
+	v             any
+	variables     map[string]any
+	options       []Option
+	optionsOutput *constructOptionsOutput
+	err           error
+}
+
+// BatchHandle refers to one operation queued on a Batch. It resolves once
+// Batch.Do returns; Err reports that operation's individual error, if any.
+type BatchHandle struct {
+	op *batchOperation
+}
+
+// Err returns the error for this operation after Batch.Do has returned. It
+// is unset (nil) before Do runs.
+func (h *BatchHandle) Err() error {
+	return h.op.err
+}
+
+// Batch accumulates Query/Mutate calls and flushes them as a single HTTP
+// request carrying a JSON array body, decoding each response slot back
+// into its corresponding handle's struct when Do returns.
+type Batch struct {
+	c   *Client
+	ops []*batchOperation
+}
+
+// NewBatch creates an empty Batch against c.
+func (c *Client) NewBatch() *Batch {
+	return &Batch{c: c}
+}
+
+// Query queues a query operation, analogous to Client.Query.
+func (b *Batch) Query(q any, variables map[string]any, options ...Option) *BatchHandle {
+	return b.add(queryOperation, q, variables, options)
+}
+
+// Mutate queues a mutation operation, analogous to Client.Mutate.
+func (b *Batch) Mutate(m any, variables map[string]any, options ...Option) *BatchHandle {
+	return b.add(mutationOperation, m, variables, options)
+}
+
+func (b *Batch) add(op operationType, v any, variables map[string]any, options []Option) *BatchHandle {
+	o := &batchOperation{op: op, v: v, variables: variables, options: options}
+	b.ops = append(b.ops, o)
+	return &BatchHandle{op: o}
+}
+
+// Do renders every queued operation, sends them as one JSON array request,
+// and decodes each response slot into its handle's struct. It returns a
+// transport-level error (the request failed, or the response shape didn't
+// match); per-operation GraphQL errors are only available via each
+// BatchHandle's Err after Do returns nil.
+//
+// The send itself goes through doWithRetry/sendOnce, the same
+// RetryPolicy-driven attempt loop and Middleware notification
+// requestWithRetry gives a single Query/Mutate call, so a client
+// configured with WithRetry/WithMiddleware keeps those guarantees for
+// batched (and WithAutoBatch-coalesced) calls too.
+func (b *Batch) Do(ctx context.Context) error {
+	if len(b.ops) == 0 {
+		return nil
+	}
+
+	items := make([]batchItem, len(b.ops))
+	for i, o := range b.ops {
+		query, optionsOutput, err := b.c.buildQueryAndOptions(o.op, o.v, o.variables, o.options...)
+		if err != nil {
+			return fmt.Errorf("batch: encoding operation %d: %w", i, err)
+		}
+		o.optionsOutput = optionsOutput
+		items[i] = batchItem{
+			Query:         query,
+			Variables:     o.variables,
+			OperationName: optionsOutput.operationName,
+		}
+	}
+
+	body, err := json.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("batch: encoding request: %w", err)
+	}
+
+	resp, errs := b.doWithRetry(ctx, body)
+	if len(errs) > 0 {
+		b.failAll(errs)
+		return fmt.Errorf("batch: request failed: %w", errs)
+	}
+	defer resp.Body.Close()
+
+	var results []batchItemResponse
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		b.failAll(err)
+		return fmt.Errorf("batch: decoding response: %w", err)
+	}
+	if len(results) != len(b.ops) {
+		err := fmt.Errorf("batch: server returned %d results for %d operations", len(results), len(b.ops))
+		b.failAll(err)
+		return err
+	}
+
+	for i, o := range b.ops {
+		r := results[i]
+		if len(r.Errors) > 0 {
+			o.err = r.Errors
+			continue
+		}
+		if len(r.Data) > 0 {
+			if err := jsonutil.UnmarshalGraphQL(r.Data, o.v); err != nil {
+				o.err = newError(ErrGraphQLDecode, err)
+				continue
+			}
+		}
+		if o.optionsOutput.extensions != nil && len(r.Extensions) > 0 {
+			if err := json.Unmarshal(r.Extensions, o.optionsOutput.extensions); err != nil {
+				o.err = newError(ErrGraphQLExtensionsDecode, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sendOnce builds and sends the batch request once, returning the raw
+// *http.Response on success. Failures are reported as Errors the same way
+// Client.sendOnce reports them - ErrRequestError wrapping a transport
+// error, or wrapping a NetworkError for a non-200 status - so isRetryable
+// treats a failed batch attempt exactly like a failed single-operation one.
+func (b *Batch) sendOnce(ctx context.Context, body []byte) (*http.Response, Errors) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, Errors{newError(ErrRequestError, fmt.Errorf("batch: constructing request: %w", err))}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.c.requestModifier != nil {
+		b.c.requestModifier(req)
+	}
+
+	resp, err := b.c.httpClient.Do(req)
+	if err != nil {
+		return nil, Errors{newError(ErrRequestError, fmt.Errorf("batch: request failed: %w", err))}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, Errors{newError(ErrRequestError, NetworkError{statusCode: resp.StatusCode})}
+	}
+
+	return resp, nil
+}
+
+// doWithRetry sends body via sendOnce, retrying per the client's
+// RetryPolicy exactly as Client.requestWithRetry does for a single
+// operation, and reporting every attempt to each registered Middleware.
+func (b *Batch) doWithRetry(ctx context.Context, body []byte) (*http.Response, Errors) {
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		resp, errs := b.sendOnce(ctx, body)
+		b.c.notifyMiddlewares(ctx, "batch", time.Since(start), errs)
+
+		if len(errs) == 0 || b.c.retryPolicy == nil || attempt >= b.c.retryPolicy.MaxRetries || !b.c.isRetryable(errs) {
+			return resp, errs
+		}
+
+		select {
+		case <-ctx.Done():
+			return resp, errs
+		case <-time.After(backoffWithJitter(*b.c.retryPolicy, attempt)):
+		}
+	}
+}
+
+func (b *Batch) failAll(err error) {
+	for _, o := range b.ops {
+		o.err = err
+	}
+}
+
+// autoBatcher coalesces concurrent Query calls issued within window into a
+// single Batch, the way a DataLoader coalesces concurrent outbound
+// requests. It is installed on a Client by WithAutoBatch.
+type autoBatcher struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	pending *Batch
+	waiters []chan struct{}
+	timer   *time.Timer
+}
+
+// WithAutoBatch returns a client where every Query call (Mutate is never
+// auto-batched, since mutations shouldn't be silently deduped/reordered)
+// issued within window of the first is coalesced into one HTTP request.
+func (c *Client) WithAutoBatch(window time.Duration) *Client {
+	return &Client{
+		url:             c.url,
+		httpClient:      c.httpClient,
+		requestModifier: c.requestModifier,
+		debug:           c.debug,
+		retryPolicy:     c.retryPolicy,
+		middlewares:     c.middlewares,
+		httpMethod:      c.httpMethod,
+		maxURLLength:    c.maxURLLength,
+		autoBatch:       &autoBatcher{window: window},
+		apqCache:        c.apqCache,
+		validation:      c.validation,
+	}
+}
+
+// enqueue adds one query operation to the in-flight (or a freshly started)
+// batch window, blocking until that window flushes or ctx is canceled.
+func (ab *autoBatcher) enqueue(ctx context.Context, c *Client, v any, variables map[string]any, options []Option) error {
+	ab.mu.Lock()
+	if ab.pending == nil {
+		ab.pending = c.NewBatch()
+		ab.timer = time.AfterFunc(ab.window, ab.flush)
+	}
+	batch := ab.pending
+	handle := batch.add(queryOperation, v, variables, options)
+	done := make(chan struct{})
+	ab.waiters = append(ab.waiters, done)
+	ab.mu.Unlock()
+
+	select {
+	case <-done:
+		return handle.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (ab *autoBatcher) flush() {
+	ab.mu.Lock()
+	batch := ab.pending
+	waiters := ab.waiters
+	ab.pending = nil
+	ab.waiters = nil
+	ab.timer = nil
+	ab.mu.Unlock()
+
+	if batch == nil {
+		return
+	}
+
+	if err := batch.Do(context.Background()); err != nil {
+		batch.failAll(err)
+	}
+	for _, w := range waiters {
+		close(w)
+	}
+}
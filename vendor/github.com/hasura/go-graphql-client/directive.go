@@ -0,0 +1,245 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Variable marks a DirectiveArg value as a reference to the operation
+// variable named string(v), rather than a literal. OperationDirective
+// renders it as `$name` and auto-declares name in the operation's variable
+// definition list.
+type Variable string
+
+// DirectiveArg is one name: value argument of an operation directive. Value
+// is either a Variable reference or a Go scalar (string, bool, int, int64,
+// float64) coerced to its GraphQL literal form.
+type DirectiveArg struct {
+	Name  string
+	Value any
+}
+
+// DirectiveArgSchema describes one argument a custom directive registered
+// via RegisterDirective accepts, so WithValidation can check usages
+// against it. GraphQLType is the argument's declared type, e.g. "String",
+// "Int!", "[String!]".
+type DirectiveArgSchema struct {
+	Name        string
+	GraphQLType string
+}
+
+var customDirectives = struct {
+	mu   sync.Mutex
+	defs map[string][]DirectiveArgSchema
+}{defs: map[string][]DirectiveArgSchema{}}
+
+// RegisterDirective declares a custom server-side directive's argument
+// schema (à la schema directives in graphql-go-tools), so
+// OperationDirective calls using name are recognized by WithValidation.
+// Re-registering name overwrites its schema.
+func RegisterDirective(name string, args ...DirectiveArgSchema) {
+	customDirectives.mu.Lock()
+	defer customDirectives.mu.Unlock()
+	customDirectives.defs[name] = args
+}
+
+func lookupDirectiveSchema(name string) ([]DirectiveArgSchema, bool) {
+	customDirectives.mu.Lock()
+	defer customDirectives.mu.Unlock()
+	args, ok := customDirectives.defs[name]
+	return args, ok
+}
+
+// operationDirective is the concrete Option OperationDirective and its
+// IncludeIf/SkipIf/Defer helpers emit.
+type operationDirective struct {
+	name string
+	args []DirectiveArg
+}
+
+func (od operationDirective) Type() OptionType {
+	return OptionTypeOperationDirective
+}
+
+// OperationDirective attaches a directive to the operation definition,
+// e.g. OperationDirective("custom", DirectiveArg{Name: "arg", Value: "v"})
+// renders `query Foo @custom(arg: "v") { ... }`. Passing more than one
+// OperationDirective option renders all of them, in the order given to
+// Query/Mutate/Exec.
+func OperationDirective(name string, args ...DirectiveArg) Option {
+	return operationDirective{name: name, args: args}
+}
+
+// IncludeIf attaches @include(if: $varName).
+func IncludeIf(varName string) Option {
+	return OperationDirective("include", DirectiveArg{Name: "if", Value: Variable(varName)})
+}
+
+// SkipIf attaches @skip(if: $varName).
+func SkipIf(varName string) Option {
+	return OperationDirective("skip", DirectiveArg{Name: "if", Value: Variable(varName)})
+}
+
+// Defer attaches @defer, or @defer(label: "label") if label is non-empty.
+func Defer(label string) Option {
+	if label == "" {
+		return OperationDirective("defer")
+	}
+	return OperationDirective("defer", DirectiveArg{Name: "label", Value: label})
+}
+
+// RenderDirectives renders every OperationDirective-derived option in
+// options, in order, as the "@name(arg: value) ..." text to splice right
+// after the operation name in the rendered query/mutation, before its
+// variable-definition parens and selection set. It also returns, sorted
+// for a deterministic definition order, every $variable name referenced by
+// a Variable-valued arg - constructQuery/constructMutation must ensure
+// these are declared in the operation's variable list alongside the ones
+// derived from struct tags, since a directive arg may reference a variable
+// that appears nowhere else in the selection set.
+func RenderDirectives(options []Option) (rendered string, referencedVars []string) {
+	var sb strings.Builder
+	seen := map[string]bool{}
+
+	for _, opt := range options {
+		od, ok := opt.(operationDirective)
+		if !ok {
+			continue
+		}
+
+		sb.WriteString(" @")
+		sb.WriteString(od.name)
+		if len(od.args) > 0 {
+			sb.WriteString("(")
+			for i, a := range od.args {
+				if i > 0 {
+					sb.WriteString(", ")
+				}
+				sb.WriteString(a.Name)
+				sb.WriteString(": ")
+				lit, refVar := renderDirectiveArgLiteral(a.Value)
+				sb.WriteString(lit)
+				if refVar != "" && !seen[refVar] {
+					seen[refVar] = true
+					referencedVars = append(referencedVars, refVar)
+				}
+			}
+			sb.WriteString(")")
+		}
+	}
+
+	sort.Strings(referencedVars)
+	return sb.String(), referencedVars
+}
+
+// spliceOperationDirectives inserts the text RenderDirectives rendered for
+// options right after the operation's name and variable-definition parens in
+// query, the string constructQuery/constructMutation produced, first
+// extending those parens with any name in referencedVars that isn't already
+// declared there. A directive arg may reference a variable that appears
+// nowhere in the selection set (e.g. IncludeIf("show") on a field that takes
+// no arguments itself), so without this a server would reject the query with
+// "variable $show is not defined". The placeholder type given to a
+// newly-declared variable is always Boolean, since every directive this
+// package renders today (@include/@skip's "if" arg) is boolean-valued;
+// declaring a non-boolean-typed variable this way would need a real type to
+// be threaded in from the caller instead.
+//
+// When query has no "query"/"mutation" keyword of its own - the shorthand
+// form emitted for an anonymous, directive-less operation - the keyword is
+// added so the directive (and any variable declarations) have somewhere to
+// attach, since an anonymous selection set alone can't carry either.
+func spliceOperationDirectives(op operationType, query, directives string, referencedVars []string) (string, error) {
+	keyword := "query"
+	if op == mutationOperation {
+		keyword = "mutation"
+	}
+
+	brace := strings.IndexByte(query, '{')
+	if brace < 0 {
+		return "", fmt.Errorf("malformed %s: no selection set found to attach directives to", keyword)
+	}
+
+	head := strings.TrimRight(query[:brace], " ")
+	if !strings.HasPrefix(query, keyword) {
+		head = keyword
+	}
+
+	head = declareReferencedVars(head, referencedVars)
+
+	return head + directives + " " + query[brace:], nil
+}
+
+// declareReferencedVars extends head - the "keyword Name(defs)" portion of
+// an operation, with or without existing defs - so that every name in
+// referencedVars not already declared there is added as "$name: Boolean",
+// merging into the existing variable-definition parens if present or adding
+// a new pair if not.
+func declareReferencedVars(head string, referencedVars []string) string {
+	open := strings.IndexByte(head, '(')
+	var before, defs, after string
+	if open < 0 {
+		before, defs, after = head, "", ""
+	} else {
+		closeIdx := strings.LastIndexByte(head, ')')
+		before, defs, after = head[:open], head[open+1:closeIdx], head[closeIdx+1:]
+	}
+
+	var toAdd []string
+	for _, name := range referencedVars {
+		if declaresVar(defs, name) {
+			continue
+		}
+		toAdd = append(toAdd, "$"+name+": Boolean")
+	}
+	if len(toAdd) == 0 {
+		return head
+	}
+
+	if defs == "" {
+		defs = strings.Join(toAdd, ", ")
+	} else {
+		defs = defs + ", " + strings.Join(toAdd, ", ")
+	}
+	return before + "(" + defs + ")" + after
+}
+
+// declaresVar reports whether defs, a "$id: ID!, $limit: Int" style
+// variable-definition list, already declares name.
+func declaresVar(defs, name string) bool {
+	for _, d := range strings.Split(defs, ",") {
+		d = strings.TrimSpace(d)
+		if colon := strings.IndexByte(d, ':'); colon >= 0 {
+			d = d[:colon]
+		}
+		if strings.TrimSpace(d) == "$"+name {
+			return true
+		}
+	}
+	return false
+}
+
+// renderDirectiveArgLiteral coerces a Go value used as a DirectiveArg.Value
+// to its GraphQL literal text. A non-empty referencedVar means v was a
+// Variable reference rather than a literal.
+func renderDirectiveArgLiteral(v any) (literal string, referencedVar string) {
+	switch x := v.(type) {
+	case Variable:
+		return "$" + string(x), string(x)
+	case string:
+		return strconv.Quote(x), ""
+	case bool:
+		return strconv.FormatBool(x), ""
+	case int:
+		return strconv.Itoa(x), ""
+	case int64:
+		return strconv.FormatInt(x, 10), ""
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), ""
+	default:
+		return fmt.Sprintf("%v", x), ""
+	}
+}
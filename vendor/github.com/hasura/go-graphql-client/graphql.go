@@ -5,14 +5,23 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/hasura/go-graphql-client/pkg/jsonutil"
 )
 
+// defaultMaxURLLength is the GET request URL length above which the client
+// falls back to POST, matching the conservative limit many proxies/CDNs and
+// browsers impose.
+const defaultMaxURLLength = 2048
+
 // Doer interface has the method required to use a type as custom http client.
 // The net/*http.Client type satisfies this interface.
 type Doer interface {
@@ -23,12 +32,65 @@ type Doer interface {
 // headers  amongst other things
 type RequestModifier func(*http.Request)
 
+// RetryPolicy configures the exponential-backoff-with-jitter retry behavior
+// installed by Client.WithRetry. A request is only retried when it fails
+// with a NetworkError whose status code is 5xx or a timeout, or with a
+// GraphQL Errors entry whose Extensions["code"] is in TransientCodes.
+type RetryPolicy struct {
+	// MaxRetries is the number of additional attempts after the first one.
+	MaxRetries int
+	// BaseDelay is the backoff delay used for the first retry; it doubles
+	// on each subsequent attempt up to MaxDelay.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay. Zero means no cap.
+	MaxDelay time.Duration
+	// TransientCodes lists the GraphQL error Extensions["code"] values
+	// that are safe to retry.
+	TransientCodes map[string]bool
+}
+
+// DefaultRetryPolicy retries up to 3 times with exponential backoff between
+// 100ms and 2s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  100 * time.Millisecond,
+		MaxDelay:   2 * time.Second,
+		TransientCodes: map[string]bool{
+			"INTERNAL_SERVER_ERROR": true,
+			"TIMEOUT":               true,
+		},
+	}
+}
+
+// RequestInfo describes one completed GraphQL operation, reported to every
+// Middleware registered via Client.WithMiddleware.
+type RequestInfo struct {
+	// Operation is the operation name set via the OperationName option, if any.
+	Operation string
+	Duration  time.Duration
+	Err       error
+}
+
+// Middleware observes a completed GraphQL operation (including ones that
+// will still be retried). It is the extension point for request/response
+// telemetry, e.g. recording an OpenTelemetry span, without forking the
+// client.
+type Middleware func(ctx context.Context, info RequestInfo)
+
 // Client is a GraphQL client.
 type Client struct {
 	url             string // GraphQL server URL.
 	httpClient      Doer
 	requestModifier RequestModifier
 	debug           bool
+	retryPolicy     *RetryPolicy
+	middlewares     []Middleware
+	httpMethod      string // Default HTTP method; empty means POST.
+	maxURLLength    int    // Max GET URL length before falling back to POST; 0 means defaultMaxURLLength.
+	autoBatch       *autoBatcher // Set by WithAutoBatch; coalesces concurrent Query calls.
+	apqCache        *apqCache    // Set by WithAutomaticPersistedQueries; enables APQ.
+	validation      *SchemaCache // Set by WithValidation; validates before every Query/Mutate.
 }
 
 // NewClient creates a GraphQL client targeting the specified GraphQL server URL.
@@ -44,6 +106,13 @@ func NewClient(url string, httpClient Doer) *Client {
 	}
 }
 
+func (c *Client) effectiveMaxURLLength() int {
+	if c.maxURLLength > 0 {
+		return c.maxURLLength
+	}
+	return defaultMaxURLLength
+}
+
 // Query executes a single GraphQL query request,
 // with a query derived from q, populating the response into it.
 // q should be a pointer to struct that corresponds to the GraphQL schema.
@@ -117,24 +186,72 @@ func (c *Client) buildQueryAndOptions(op operationType, v any, variables map[str
 	if err != nil {
 		return "", nil, Errors{newError(ErrGraphQLEncode, err)}
 	}
+
+	if directives, referencedVars := RenderDirectives(options); directives != "" {
+		for _, name := range referencedVars {
+			if _, ok := variables[name]; !ok {
+				return "", nil, Errors{newError(ErrGraphQLEncode, fmt.Errorf("operation directive references variable $%s, which was not supplied in variables", name))}
+			}
+		}
+		query, err = spliceOperationDirectives(op, query, directives, referencedVars)
+		if err != nil {
+			return "", nil, Errors{newError(ErrGraphQLEncode, err)}
+		}
+	}
+
 	return query, optionOutput, nil
 }
 
-// Request the common method that send graphql request
-func (c *Client) request(ctx context.Context, query string, variables map[string]any, options *constructOptionsOutput) ([]byte, []byte, *http.Response, io.Reader, Errors) {
+// buildHTTPRequest renders query/variables/options into an *http.Request,
+// choosing between GET and POST per the client/option httpMethod and the
+// configured max URL length. GET is rejected for mutations.
+func (c *Client) buildHTTPRequest(ctx context.Context, op operationType, query string, variables map[string]any, options *constructOptionsOutput) (*http.Request, *bytes.Reader, *Error) {
+	if (options != nil && options.forceMultipart) || hasUploads(variables) {
+		req, err := c.buildMultipartRequest(ctx, query, variables, options)
+		if err != nil {
+			e := newError(ErrRequestError, fmt.Errorf("problem constructing multipart request: %w", err))
+			return nil, nil, &e
+		}
+		return req, bytes.NewReader(nil), nil
+	}
+
+	method := c.httpMethod
+	if options != nil && options.httpMethod != "" {
+		method = options.httpMethod
+	}
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	if method == http.MethodGet {
+		if op == mutationOperation {
+			e := newError(ErrRequestError, fmt.Errorf("GET is not supported for mutations"))
+			return nil, nil, &e
+		}
+
+		req, ok, err := c.buildGetRequest(ctx, query, variables, options)
+		if err != nil {
+			e := newError(ErrRequestError, fmt.Errorf("problem constructing GET request: %w", err))
+			return nil, nil, &e
+		}
+		if ok {
+			return req, bytes.NewReader(nil), nil
+		}
+		// Falls through to POST: the GET URL would exceed maxURLLength.
+	}
+
 	in := GraphQLRequestPayload{
 		Query:     query,
 		Variables: variables,
 	}
-
 	if options != nil {
 		in.OperationName = options.operationName
 	}
 
 	var buf bytes.Buffer
-	err := json.NewEncoder(&buf).Encode(in)
-	if err != nil {
-		return nil, nil, nil, nil, Errors{newError(ErrGraphQLEncode, err)}
+	if err := json.NewEncoder(&buf).Encode(in); err != nil {
+		e := newError(ErrGraphQLEncode, err)
+		return nil, nil, &e
 	}
 
 	reqReader := bytes.NewReader(buf.Bytes())
@@ -144,14 +261,75 @@ func (c *Client) request(ctx context.Context, query string, variables map[string
 		if c.debug {
 			e = e.withRequest(request, reqReader)
 		}
-		return nil, nil, nil, nil, Errors{e}
+		return nil, nil, &e
 	}
 	request.Header.Add("Content-Type", "application/json")
 
+	return request, reqReader, nil
+}
+
+// buildGetRequest renders a GraphQL GET request the way common GraphQL
+// gateways expect: ?query=...&operationName=...&variables=<json>. ok is
+// false when the resulting URL would exceed the client's configured max
+// length, signaling the caller to fall back to POST.
+func (c *Client) buildGetRequest(ctx context.Context, query string, variables map[string]any, options *constructOptionsOutput) (req *http.Request, ok bool, err error) {
+	q := url.Values{}
+	q.Set("query", query)
+	if options != nil && options.operationName != "" {
+		q.Set("operationName", options.operationName)
+	}
+	if len(variables) > 0 {
+		varsJSON, err := json.Marshal(variables)
+		if err != nil {
+			return nil, false, err
+		}
+		q.Set("variables", string(varsJSON))
+	}
+
+	fullURL := c.url + "?" + q.Encode()
+	if len(fullURL) > c.effectiveMaxURLLength() {
+		return nil, false, nil
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	return req, true, nil
+}
+
+// sniffOperationType guesses whether a raw query string (as used by
+// Exec/ExecRaw, which bypass the typed Query/Mutate API) is a query or
+// mutation, so GET-for-mutations can still be rejected.
+func sniffOperationType(query string) operationType {
+	if strings.HasPrefix(strings.TrimSpace(query), "mutation") {
+		return mutationOperation
+	}
+	return queryOperation
+}
+
+// Request the common method that send graphql request
+func (c *Client) request(ctx context.Context, op operationType, query string, variables map[string]any, options *constructOptionsOutput) ([]byte, []byte, *http.Response, io.Reader, Errors) {
+	if c.apqCache != nil {
+		return c.requestAPQ(ctx, op, query, variables, options)
+	}
+
+	request, reqReader, buildErr := c.buildHTTPRequest(ctx, op, query, variables, options)
+	if buildErr != nil {
+		return nil, nil, nil, nil, Errors{*buildErr}
+	}
+
 	if c.requestModifier != nil {
 		c.requestModifier(request)
 	}
 
+	return c.sendOnce(request, reqReader)
+}
+
+// sendOnce executes an already-built request and decodes its GraphQL
+// response envelope. Both request() and the Automatic Persisted Queries
+// path in requestAPQ share it, since APQ may need to send a request twice.
+func (c *Client) sendOnce(request *http.Request, reqReader *bytes.Reader) ([]byte, []byte, *http.Response, io.Reader, Errors) {
 	resp, err := c.httpClient.Do(request)
 
 	if c.debug {
@@ -253,7 +431,7 @@ func (c *Client) doRaw(ctx context.Context, op operationType, v any, variables m
 	if err != nil {
 		return nil, err
 	}
-	data, _, _, _, errs := c.request(ctx, query, variables, optionsOutput)
+	data, _, _, _, errs := c.requestWithRetry(ctx, op, query, variables, optionsOutput)
 	if len(errs) > 0 {
 		return data, errs
 	}
@@ -263,11 +441,21 @@ func (c *Client) doRaw(ctx context.Context, op operationType, v any, variables m
 
 // do executes a single GraphQL operation and unmarshal json.
 func (c *Client) do(ctx context.Context, op operationType, v any, variables map[string]any, options ...Option) error {
+	if c.validation != nil {
+		if err := c.validateAgainstRoot(ctx, op, v, variables, options); err != nil {
+			return err
+		}
+	}
+
+	if c.autoBatch != nil && op == queryOperation {
+		return c.autoBatch.enqueue(ctx, c, v, variables, options)
+	}
+
 	query, optionsOutput, err := c.buildQueryAndOptions(op, v, variables, options...)
 	if err != nil {
 		return err
 	}
-	data, extData, resp, respBuf, errs := c.request(ctx, query, variables, optionsOutput)
+	data, extData, resp, respBuf, errs := c.requestWithRetry(ctx, op, query, variables, optionsOutput)
 
 	return c.processResponse(v, data, optionsOutput.extensions, extData, resp, respBuf, errs)
 }
@@ -280,7 +468,7 @@ func (c *Client) Exec(ctx context.Context, query string, v any, variables map[st
 		return err
 	}
 
-	data, extData, resp, respBuf, errs := c.request(ctx, query, variables, optionsOutput)
+	data, extData, resp, respBuf, errs := c.requestWithRetry(ctx, sniffOperationType(query), query, variables, optionsOutput)
 	return c.processResponse(v, data, optionsOutput.extensions, extData, resp, respBuf, errs)
 }
 
@@ -292,7 +480,7 @@ func (c *Client) ExecRaw(ctx context.Context, query string, variables map[string
 		return nil, err
 	}
 
-	data, _, _, _, errs := c.request(ctx, query, variables, optionsOutput)
+	data, _, _, _, errs := c.requestWithRetry(ctx, sniffOperationType(query), query, variables, optionsOutput)
 	if len(errs) > 0 {
 		return data, errs
 	}
@@ -308,13 +496,92 @@ func (c *Client) ExecRawWithExtensions(ctx context.Context, query string, variab
 		return nil, nil, err
 	}
 
-	data, ext, _, _, errs := c.request(ctx, query, variables, optionsOutput)
+	data, ext, _, _, errs := c.requestWithRetry(ctx, sniffOperationType(query), query, variables, optionsOutput)
 	if len(errs) > 0 {
 		return data, ext, errs
 	}
 	return data, ext, nil
 }
 
+// requestWithRetry wraps request with the client's RetryPolicy (if any) and
+// reports each attempt's timing to every registered Middleware.
+func (c *Client) requestWithRetry(ctx context.Context, op operationType, query string, variables map[string]any, options *constructOptionsOutput) ([]byte, []byte, *http.Response, io.Reader, Errors) {
+	var operation string
+	if options != nil {
+		operation = options.operationName
+	}
+
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		data, extData, resp, respBuf, errs := c.request(ctx, op, query, variables, options)
+		c.notifyMiddlewares(ctx, operation, time.Since(start), errs)
+
+		if len(errs) == 0 || c.retryPolicy == nil || attempt >= c.retryPolicy.MaxRetries || !c.isRetryable(errs) {
+			return data, extData, resp, respBuf, errs
+		}
+
+		select {
+		case <-ctx.Done():
+			return data, extData, resp, respBuf, errs
+		case <-time.After(backoffWithJitter(*c.retryPolicy, attempt)):
+		}
+	}
+}
+
+// isRetryable reports whether errs contains a NetworkError with a 5xx or
+// timeout status code, or a GraphQL error whose Extensions["code"] is
+// configured as transient in the client's RetryPolicy.
+func (c *Client) isRetryable(errs Errors) bool {
+	for _, e := range errs {
+		var ne NetworkError
+		if errors.As(e, &ne) {
+			if ne.statusCode >= 500 || ne.statusCode == http.StatusRequestTimeout {
+				return true
+			}
+			continue
+		}
+
+		if code, ok := e.Extensions["code"].(string); ok && c.retryPolicy.TransientCodes[code] {
+			return true
+		}
+	}
+	return false
+}
+
+// backoffWithJitter computes the delay before the given retry attempt
+// (0-indexed), doubling BaseDelay each attempt, capped at MaxDelay, with up
+// to 50% jitter to avoid thundering-herd retries.
+func backoffWithJitter(policy RetryPolicy, attempt int) time.Duration {
+	delay := policy.BaseDelay << attempt
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)))
+	return delay/2 + jitter/2
+}
+
+// notifyMiddlewares reports a completed attempt to every registered
+// Middleware. Errs is reported as a plain error so Middleware doesn't need
+// to import the Errors type to check for nil.
+func (c *Client) notifyMiddlewares(ctx context.Context, operation string, duration time.Duration, errs Errors) {
+	if len(c.middlewares) == 0 {
+		return
+	}
+
+	var err error
+	if len(errs) > 0 {
+		err = errs
+	}
+
+	info := RequestInfo{Operation: operation, Duration: duration, Err: err}
+	for _, mw := range c.middlewares {
+		mw(ctx, info)
+	}
+}
+
 func (c *Client) processResponse(v any, data []byte, extensions any, rawExtensions []byte, resp *http.Response, respBuf io.Reader, errs Errors) error {
 	if len(data) > 0 {
 		err := jsonutil.UnmarshalGraphQL(data, v)
@@ -350,6 +617,14 @@ func (c *Client) WithRequestModifier(f RequestModifier) *Client {
 		url:             c.url,
 		httpClient:      c.httpClient,
 		requestModifier: f,
+		debug:           c.debug,
+		retryPolicy:     c.retryPolicy,
+		middlewares:     c.middlewares,
+		httpMethod:      c.httpMethod,
+		maxURLLength:    c.maxURLLength,
+		autoBatch:       c.autoBatch,
+		apqCache:        c.apqCache,
+		validation:      c.validation,
 	}
 }
 
@@ -360,6 +635,97 @@ func (c *Client) WithDebug(debug bool) *Client {
 		httpClient:      c.httpClient,
 		requestModifier: c.requestModifier,
 		debug:           debug,
+		retryPolicy:     c.retryPolicy,
+		middlewares:     c.middlewares,
+		httpMethod:      c.httpMethod,
+		maxURLLength:    c.maxURLLength,
+		autoBatch:       c.autoBatch,
+		apqCache:        c.apqCache,
+		validation:      c.validation,
+	}
+}
+
+// WithRetry returns a copy of the client that retries failed operations
+// according to policy. Only NetworkErrors with a 5xx/timeout status code and
+// GraphQL errors whose Extensions["code"] is in policy.TransientCodes are
+// retried; everything else is returned on the first attempt.
+func (c *Client) WithRetry(policy RetryPolicy) *Client {
+	return &Client{
+		url:             c.url,
+		httpClient:      c.httpClient,
+		requestModifier: c.requestModifier,
+		debug:           c.debug,
+		retryPolicy:     &policy,
+		middlewares:     c.middlewares,
+		httpMethod:      c.httpMethod,
+		maxURLLength:    c.maxURLLength,
+		autoBatch:       c.autoBatch,
+		apqCache:        c.apqCache,
+		validation:      c.validation,
+	}
+}
+
+// WithMiddleware returns a copy of the client with the given Middleware
+// appended to its chain. Each Middleware is invoked once per attempt
+// (including attempts that are subsequently retried), so it can be used to
+// record per-request timings/spans without forking the client.
+func (c *Client) WithMiddleware(mw ...Middleware) *Client {
+	middlewares := make([]Middleware, 0, len(c.middlewares)+len(mw))
+	middlewares = append(middlewares, c.middlewares...)
+	middlewares = append(middlewares, mw...)
+
+	return &Client{
+		url:             c.url,
+		httpClient:      c.httpClient,
+		requestModifier: c.requestModifier,
+		debug:           c.debug,
+		retryPolicy:     c.retryPolicy,
+		middlewares:     middlewares,
+		httpMethod:      c.httpMethod,
+		maxURLLength:    c.maxURLLength,
+		autoBatch:       c.autoBatch,
+		apqCache:        c.apqCache,
+		validation:      c.validation,
+	}
+}
+
+// WithHTTPMethod returns a copy of the client that issues GraphQL operations
+// using method by default (e.g. http.MethodGet for CDN-cacheable queries).
+// Mutations always use POST regardless of this setting. A per-call
+// HTTPMethod option overrides this default, and GET requests longer than
+// the configured max URL length (see WithMaxURLLength) fall back to POST.
+func (c *Client) WithHTTPMethod(method string) *Client {
+	return &Client{
+		url:             c.url,
+		httpClient:      c.httpClient,
+		requestModifier: c.requestModifier,
+		debug:           c.debug,
+		retryPolicy:     c.retryPolicy,
+		middlewares:     c.middlewares,
+		httpMethod:      method,
+		maxURLLength:    c.maxURLLength,
+		autoBatch:       c.autoBatch,
+		apqCache:        c.apqCache,
+		validation:      c.validation,
+	}
+}
+
+// WithMaxURLLength returns a copy of the client that falls back to POST once
+// a GET request's URL would exceed n bytes. n <= 0 restores the default
+// (defaultMaxURLLength).
+func (c *Client) WithMaxURLLength(n int) *Client {
+	return &Client{
+		url:             c.url,
+		httpClient:      c.httpClient,
+		requestModifier: c.requestModifier,
+		debug:           c.debug,
+		retryPolicy:     c.retryPolicy,
+		middlewares:     c.middlewares,
+		httpMethod:      c.httpMethod,
+		maxURLLength:    n,
+		autoBatch:       c.autoBatch,
+		apqCache:        c.apqCache,
+		validation:      c.validation,
 	}
 }
 
@@ -0,0 +1,247 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Upload wraps a file to be sent over the GraphQL multipart request spec
+// (https://github.com/jaydenseric/graphql-multipart-request-spec). Place a
+// *Upload anywhere inside the variables passed to Query/Mutate/Exec -
+// including inside a slice, for an `[Upload!]!` variable - and the client
+// switches transport to multipart/form-data automatically.
+type Upload struct {
+	File        io.Reader
+	Filename    string
+	ContentType string
+}
+
+// uploadRef records where a single *Upload occurrence was found while
+// walking variables, as a dot/index path rooted at "variables", e.g.
+// "variables.file" or "variables.files.0".
+type uploadRef struct {
+	upload *Upload
+	path   string
+}
+
+// stripUploads walks v (the value of a variables entry, or a nested map/
+// slice within it), replacing every *Upload with nil and recording its
+// path. It returns a deep-enough copy so the original variables map passed
+// by the caller is left untouched.
+//
+// map[string]any and []any - the shapes a variables map built from untyped
+// JSON-like literals takes - get a fast, allocation-light path. Anything
+// else (a concrete []*Upload, a map[string]*Upload, a named slice/map type
+// generated from a GraphQL input type, ...) falls through to a reflect walk
+// of any Ptr/Interface/Slice/Array/Map so an *Upload nested inside one is
+// still found, since callers building variables from typed Go structs
+// rarely hand-wrap every field as map[string]any/[]any.
+func stripUploads(v any, path string, refs *[]uploadRef) any {
+	switch x := v.(type) {
+	case *Upload:
+		*refs = append(*refs, uploadRef{upload: x, path: path})
+		return nil
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make(map[string]any, len(x))
+		for _, k := range keys {
+			out[k] = stripUploads(x[k], path+"."+k, refs)
+		}
+		return out
+	case []any:
+		out := make([]any, len(x))
+		for i, e := range x {
+			out[i] = stripUploads(e, path+"."+strconv.Itoa(i), refs)
+		}
+		return out
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return v
+		}
+		return stripUploads(rv.Elem().Interface(), path, refs)
+	case reflect.Slice, reflect.Array:
+		out := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			stripped := stripUploads(rv.Index(i).Interface(), path+"."+strconv.Itoa(i), refs)
+			if stripped != nil {
+				out.Index(i).Set(reflect.ValueOf(stripped))
+			}
+		}
+		return out.Interface()
+	case reflect.Map:
+		out := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			stripped := stripUploads(rv.MapIndex(k).Interface(), path+"."+fmt.Sprint(k.Interface()), refs)
+			if stripped != nil {
+				out.SetMapIndex(k, reflect.ValueOf(stripped))
+			}
+		}
+		return out.Interface()
+	default:
+		return v
+	}
+}
+
+// collectUploads strips every *Upload out of variables, returning the
+// sanitized copy (files replaced with null, safe to JSON-encode as the
+// "operations" part) and the path of every occurrence found, in traversal
+// order. Callers group these by *Upload identity to assign multipart part
+// indices, since the same file may be referenced at more than one path.
+func collectUploads(variables map[string]any) (map[string]any, []uploadRef) {
+	var refs []uploadRef
+	cleaned, _ := stripUploads(variables, "variables", &refs).(map[string]any)
+	return cleaned, refs
+}
+
+// hasUploads reports whether variables contains at least one *Upload,
+// without allocating the stripped copy. See stripUploads for why it also
+// walks arbitrary Ptr/Interface/Slice/Array/Map shapes via reflect, not
+// just map[string]any/[]any.
+func hasUploads(v any) bool {
+	switch x := v.(type) {
+	case *Upload:
+		return true
+	case map[string]any:
+		for _, e := range x {
+			if hasUploads(e) {
+				return true
+			}
+		}
+		return false
+	case []any:
+		for _, e := range x {
+			if hasUploads(e) {
+				return true
+			}
+		}
+		return false
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return !rv.IsNil() && hasUploads(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if hasUploads(rv.Index(i).Interface()) {
+				return true
+			}
+		}
+	case reflect.Map:
+		for _, k := range rv.MapKeys() {
+			if hasUploads(rv.MapIndex(k).Interface()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildMultipartRequest renders query/variables/options as a
+// multipart/form-data request per the GraphQL multipart request spec: an
+// "operations" part with the JSON body (files nulled out), a "map" part
+// mapping file part names to the variable paths that reference them, and
+// one part per distinct file. The body streams through an io.Pipe so file
+// contents are never buffered in full.
+func (c *Client) buildMultipartRequest(ctx context.Context, query string, variables map[string]any, options *constructOptionsOutput) (*http.Request, error) {
+	cleanedVars, refs := collectUploads(variables)
+
+	// Group paths by the identity of the *Upload they reference, assigning
+	// each distinct file a stable part index in first-seen order.
+	order := make([]*Upload, 0, len(refs))
+	paths := map[*Upload][]string{}
+	seen := map[*Upload]bool{}
+	for _, ref := range refs {
+		if !seen[ref.upload] {
+			seen[ref.upload] = true
+			order = append(order, ref.upload)
+		}
+		paths[ref.upload] = append(paths[ref.upload], ref.path)
+	}
+
+	fileMap := make(map[string][]string, len(order))
+	for i, up := range order {
+		fileMap[strconv.Itoa(i)] = paths[up]
+	}
+
+	in := GraphQLRequestPayload{
+		Query:     query,
+		Variables: cleanedVars,
+	}
+	if options != nil {
+		in.OperationName = options.operationName
+	}
+	operationsJSON, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("problem encoding operations part: %w", err)
+	}
+	mapJSON, err := json.Marshal(fileMap)
+	if err != nil {
+		return nil, fmt.Errorf("problem encoding map part: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	go func() {
+		err := func() error {
+			if err := mw.WriteField("operations", string(operationsJSON)); err != nil {
+				return err
+			}
+			if err := mw.WriteField("map", string(mapJSON)); err != nil {
+				return err
+			}
+			for i, up := range order {
+				header := make(map[string][]string)
+				contentType := up.ContentType
+				if contentType == "" {
+					contentType = "application/octet-stream"
+				}
+				filename := up.Filename
+				if filename == "" {
+					filename = strconv.Itoa(i)
+				}
+				header["Content-Disposition"] = []string{
+					fmt.Sprintf(`form-data; name="%s"; filename="%s"`, strconv.Itoa(i), filename),
+				}
+				header["Content-Type"] = []string{contentType}
+
+				part, err := mw.CreatePart(header)
+				if err != nil {
+					return err
+				}
+				if _, err := io.Copy(part, up.File); err != nil {
+					return err
+				}
+			}
+			return mw.Close()
+		}()
+		pw.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, pr)
+	if err != nil {
+		return nil, fmt.Errorf("problem constructing request: %w", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req, nil
+}
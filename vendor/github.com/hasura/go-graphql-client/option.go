@@ -5,6 +5,8 @@ type OptionType string
 
 const (
 	OptionTypeOperationDirective OptionType = "operation_directive"
+	OptionTypeHTTPMethod         OptionType = "http_method"
+	OptionTypeForceMultipart     OptionType = "force_multipart"
 )
 
 // Option abstracts an extra render interface for the query string
@@ -46,3 +48,35 @@ func (ono bindExtensionsOption) Type() OptionType {
 func BindExtensions(value any) Option {
 	return bindExtensionsOption{value: value}
 }
+
+// httpMethodOption overrides the HTTP method for a single call.
+type httpMethodOption struct {
+	method string
+}
+
+func (hmo httpMethodOption) Type() OptionType {
+	return OptionTypeHTTPMethod
+}
+
+// HTTPMethod overrides the HTTP method used for a single Query/Mutate call,
+// e.g. to force http.MethodGet on a CDN-cacheable read. It takes precedence
+// over the client-wide default set by Client.WithHTTPMethod. GET is rejected
+// for mutations. See also Client.WithHTTPMethod.
+func HTTPMethod(method string) Option {
+	return httpMethodOption{method: method}
+}
+
+// forceMultipartOption makes the client send the request as
+// multipart/form-data even when variables carry no Upload value, for
+// gateways that require the multipart request spec unconditionally.
+type forceMultipartOption struct{}
+
+func (forceMultipartOption) Type() OptionType {
+	return OptionTypeForceMultipart
+}
+
+// ForceMultipart forces the multipart/form-data transport for a single
+// Query/Mutate call. See also Upload.
+func ForceMultipart() Option {
+	return forceMultipartOption{}
+}
@@ -0,0 +1,247 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// GraphQLRequestPayload is the JSON-encoded body of a plain (non-APQ,
+// non-multipart) GraphQL-over-HTTP POST request.
+// https://graphql.org/learn/serving-over-http/#post-request
+type GraphQLRequestPayload struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+}
+
+// constructOptionsOutput collects every Option's effect on a single
+// Query/Mutate/Exec/Subscribe call, computed once by constructOptions and
+// threaded through the rest of the request pipeline (buildHTTPRequest,
+// requestAPQ, Batch.Do, Subscribe) instead of the original []Option slice.
+type constructOptionsOutput struct {
+	operationName  string
+	extensions     any
+	forceMultipart bool
+	httpMethod     string
+}
+
+// constructOptions applies every option in options to a fresh
+// constructOptionsOutput. OperationDirective options are deliberately not
+// handled here - their text is rendered and spliced into the query
+// separately, by RenderDirectives/spliceOperationDirectives, since that
+// happens after constructQuery/constructMutation has already returned.
+func constructOptions(options []Option) (*constructOptionsOutput, error) {
+	output := &constructOptionsOutput{}
+
+	for _, option := range options {
+		switch o := option.(type) {
+		case operationNameOption:
+			output.operationName = o.name
+		case bindExtensionsOption:
+			output.extensions = o.value
+		case httpMethodOption:
+			output.httpMethod = o.method
+		case forceMultipartOption:
+			output.forceMultipart = true
+		}
+	}
+
+	return output, nil
+}
+
+// constructQuery renders v's struct-tag-derived selection set and
+// variables' declaration list into a "query ... { ... }" string.
+func constructQuery(v any, variables map[string]any, options ...Option) (string, *constructOptionsOutput, error) {
+	return constructOperation("query", v, variables, options...)
+}
+
+// constructMutation is constructQuery for a "mutation ... { ... }".
+func constructMutation(v any, variables map[string]any, options ...Option) (string, *constructOptionsOutput, error) {
+	return constructOperation("mutation", v, variables, options...)
+}
+
+// constructSubscription is constructQuery for a "subscription ... { ... }",
+// additionally returning the resolved operation name since Subscribe needs
+// it alongside the rendered text.
+func constructSubscription(v any, variables map[string]any, options ...Option) (string, *constructOptionsOutput, error) {
+	return constructOperation("subscription", v, variables, options...)
+}
+
+func constructOperation(keyword string, v any, variables map[string]any, options ...Option) (string, *constructOptionsOutput, error) {
+	selection, err := query(v)
+	if err != nil {
+		return "", nil, err
+	}
+
+	optionsOutput, err := constructOptions(options)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(variables) == 0 && optionsOutput.operationName == "" {
+		return keyword + selection, optionsOutput, nil
+	}
+
+	return fmt.Sprintf("%s %s(%s)%s", keyword, optionsOutput.operationName, queryArguments(variables), selection), optionsOutput, nil
+}
+
+// queryArguments renders variables' operation-level declaration list, e.g.
+// map[string]any{"id": "x", "limit": 10} -> "$id:String!$limit:Int!".
+func queryArguments(variables map[string]any) string {
+	keys := make([]string, 0, len(variables))
+	for k := range variables {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		_, _ = io.WriteString(&buf, "$")
+		_, _ = io.WriteString(&buf, k)
+		_, _ = io.WriteString(&buf, ":")
+		writeArgumentType(&buf, reflect.TypeOf(variables[k]), true)
+	}
+	return buf.String()
+}
+
+// writeArgumentType writes a minified GraphQL type for t to w. value
+// reports whether t is a value (required, "!"-suffixed) or pointer
+// (optional) type.
+func writeArgumentType(w io.Writer, t reflect.Type, value bool) {
+	if t == nil {
+		// variables[k] was a untyped nil; there's no Go type to infer a
+		// GraphQL type from, so declare it as the most permissive shape.
+		_, _ = io.WriteString(w, "String")
+		return
+	}
+
+	if t.Kind() == reflect.Ptr {
+		writeArgumentType(w, t.Elem(), false)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		_, _ = io.WriteString(w, "[")
+		writeArgumentType(w, t.Elem(), true)
+		_, _ = io.WriteString(w, "]")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, _ = io.WriteString(w, "Int")
+	case reflect.Float32, reflect.Float64:
+		_, _ = io.WriteString(w, "Float")
+	case reflect.Bool:
+		_, _ = io.WriteString(w, "Boolean")
+	default:
+		n := t.Name()
+		if n == "string" {
+			n = "String"
+		}
+		_, _ = io.WriteString(w, n)
+	}
+
+	if value {
+		_, _ = io.WriteString(w, "!")
+	}
+}
+
+// query uses writeQuery to recursively construct a minified selection set
+// string from the struct-tagged fields of v.
+//
+// E.g. struct{ User struct{ID string} `graphql:"user(id: $id)"` } ->
+// "{user(id: $id){id}}".
+func query(v any) (string, error) {
+	var buf bytes.Buffer
+	if err := writeQuery(&buf, reflect.TypeOf(v), reflect.ValueOf(v), false); err != nil {
+		return "", fmt.Errorf("failed to write query: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// writeQuery writes a minified selection set for t to w. Each field's
+// graphql struct tag - this package's "name(arg: $var, ...)"/"alias:name(...)"
+// selection syntax, the same tag parseGraphQLFieldTag reads for Validate -
+// is written out verbatim as that field's selection header; an untagged
+// field falls back to its Go name lower-cased the same way
+// parseGraphQLFieldTag does, so query() and Validate agree on field names.
+// If inline is true, t's fields are spliced directly into the parent
+// selection set instead of getting their own "{ }".
+func writeQuery(w io.Writer, t reflect.Type, v reflect.Value, inline bool) error {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return writeQuery(w, t.Elem(), elemSafe(v), false)
+	case reflect.Struct:
+		if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+			// A custom scalar (e.g. a Time/Decimal-like type) implementing
+			// its own JSON decoding: treat it as a leaf, not a selection.
+			return nil
+		}
+
+		if !inline {
+			_, _ = io.WriteString(w, "{")
+		}
+		iter := 0
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" { // unexported
+				continue
+			}
+			tag, tagged := f.Tag.Lookup("graphql")
+			if tag == "-" {
+				continue
+			}
+
+			if iter != 0 {
+				_, _ = io.WriteString(w, ",")
+			}
+			iter++
+
+			inlineField := f.Anonymous && !tagged
+			if !inlineField {
+				if tagged {
+					_, _ = io.WriteString(w, tag)
+				} else {
+					_, _ = io.WriteString(w, lowerFirst(f.Name))
+				}
+			}
+			if err := writeQuery(w, f.Type, fieldSafe(v, i), inlineField); err != nil {
+				return fmt.Errorf("failed to write query for struct field %q: %w", f.Name, err)
+			}
+		}
+		if !inline {
+			_, _ = io.WriteString(w, "}")
+		}
+	case reflect.Slice, reflect.Array:
+		return writeQuery(w, t.Elem(), indexSafe(v, 0), false)
+	case reflect.Map:
+		return fmt.Errorf("map fields are not supported in query structs, got %v", t)
+	}
+	return nil
+}
+
+func elemSafe(v reflect.Value) reflect.Value {
+	if v.IsValid() && !v.IsNil() {
+		return v.Elem()
+	}
+	return reflect.Value{}
+}
+
+func indexSafe(v reflect.Value, i int) reflect.Value {
+	if v.IsValid() && i < v.Len() {
+		return v.Index(i)
+	}
+	return reflect.Value{}
+}
+
+func fieldSafe(v reflect.Value, i int) reflect.Value {
+	if v.IsValid() {
+		return v.Field(i)
+	}
+	return reflect.Value{}
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
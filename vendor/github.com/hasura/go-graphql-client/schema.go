@@ -0,0 +1,533 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// introspectionQuery is the standard GraphQL introspection query, trimmed
+// to the fields SchemaCache/Validate actually use.
+const introspectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    types {
+      kind
+      name
+      fields(includeDeprecated: true) {
+        name
+        args { name type { ...TypeRef } defaultValue }
+        type { ...TypeRef }
+      }
+      enumValues(includeDeprecated: true) { name }
+    }
+    directives {
+      name
+      locations
+      args { name type { ...TypeRef } defaultValue }
+    }
+  }
+}
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType { kind name ofType { kind name ofType { kind name } } }
+}`
+
+type introspectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   string                `json:"name"`
+	OfType *introspectionTypeRef `json:"ofType"`
+}
+
+// String renders the GraphQL type syntax (e.g. "[String!]!") from the
+// nested NON_NULL/LIST wrapper chain introspection reports.
+func (t *introspectionTypeRef) String() string {
+	if t == nil {
+		return ""
+	}
+	switch t.Kind {
+	case "NON_NULL":
+		return t.OfType.String() + "!"
+	case "LIST":
+		return "[" + t.OfType.String() + "]"
+	default:
+		return t.Name
+	}
+}
+
+// baseName unwraps the NON_NULL/LIST wrapper chain and returns the named
+// type underneath, e.g. "[ID!]!" -> "ID".
+func (t *introspectionTypeRef) baseName() string {
+	for t != nil && (t.Kind == "NON_NULL" || t.Kind == "LIST") {
+		t = t.OfType
+	}
+	if t == nil {
+		return ""
+	}
+	return t.Name
+}
+
+type introspectionInputValue struct {
+	Name         string                `json:"name"`
+	Type         *introspectionTypeRef `json:"type"`
+	DefaultValue *string               `json:"defaultValue"`
+}
+
+// required reports whether an argument/input value must be supplied: its
+// type is non-null and it has no default to fall back on.
+func (iv *introspectionInputValue) required() bool {
+	return iv.Type != nil && iv.Type.Kind == "NON_NULL" && iv.DefaultValue == nil
+}
+
+type introspectionField struct {
+	Name string                     `json:"name"`
+	Args []introspectionInputValue  `json:"args"`
+	Type *introspectionTypeRef      `json:"type"`
+}
+
+type introspectionEnumValue struct {
+	Name string `json:"name"`
+}
+
+func (t *introspectionNamedType) hasEnumValue(name string) bool {
+	for _, v := range t.EnumValues {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type introspectionNamedType struct {
+	Kind       string                   `json:"kind"`
+	Name       string                   `json:"name"`
+	Fields     []introspectionField     `json:"fields"`
+	EnumValues []introspectionEnumValue `json:"enumValues"`
+}
+
+type introspectionDirective struct {
+	Name      string                    `json:"name"`
+	Locations []string                  `json:"locations"`
+	Args      []introspectionInputValue `json:"args"`
+}
+
+type introspectionSchema struct {
+	QueryType    *struct{ Name string } `json:"queryType"`
+	MutationType *struct{ Name string } `json:"mutationType"`
+	Types        []introspectionNamedType `json:"types"`
+	Directives   []introspectionDirective `json:"directives"`
+}
+
+func (s *introspectionSchema) typeByName(name string) *introspectionNamedType {
+	for i := range s.Types {
+		if s.Types[i].Name == name {
+			return &s.Types[i]
+		}
+	}
+	return nil
+}
+
+func (s *introspectionSchema) directiveByName(name string) *introspectionDirective {
+	for i := range s.Directives {
+		if s.Directives[i].Name == name {
+			return &s.Directives[i]
+		}
+	}
+	return nil
+}
+
+func (t *introspectionNamedType) fieldByName(name string) *introspectionField {
+	for i := range t.Fields {
+		if t.Fields[i].Name == name {
+			return &t.Fields[i]
+		}
+	}
+	return nil
+}
+
+func (f *introspectionField) argByName(name string) *introspectionInputValue {
+	for i := range f.Args {
+		if f.Args[i].Name == name {
+			return &f.Args[i]
+		}
+	}
+	return nil
+}
+
+// schemaCacheFile is the on-disk shape SchemaCache persists between runs.
+type schemaCacheFile struct {
+	FetchedAt time.Time            `json:"fetchedAt"`
+	Schema    *introspectionSchema `json:"schema"`
+}
+
+// SchemaCache fetches a GraphQL server's introspection document once (per
+// ttl), optionally persisting it to cacheFile, and uses it to back
+// Client.Validate / WithValidation. This catches schema drift - an unknown
+// field, a renamed argument, a stale enum value, a directive used outside
+// its declared locations - at build/test time instead of at the server.
+type SchemaCache struct {
+	client    *Client
+	ttl       time.Duration
+	cacheFile string
+
+	mu        sync.Mutex
+	schema    *introspectionSchema
+	fetchedAt time.Time
+}
+
+// NewSchemaCache creates a SchemaCache that introspects via client. ttl<=0
+// means the fetched schema never expires. cacheFile, if non-empty, is
+// consulted before the network round-trip and rewritten after every live
+// fetch, so repeated short-lived processes (e.g. CI runs) don't each pay
+// for an introspection query.
+func NewSchemaCache(client *Client, ttl time.Duration, cacheFile string) *SchemaCache {
+	return &SchemaCache{client: client, ttl: ttl, cacheFile: cacheFile}
+}
+
+// Get returns the cached schema, fetching (or re-fetching, past ttl) it
+// first if necessary.
+func (sc *SchemaCache) Get(ctx context.Context) (*introspectionSchema, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.schema != nil && (sc.ttl <= 0 || time.Since(sc.fetchedAt) < sc.ttl) {
+		return sc.schema, nil
+	}
+
+	if sc.schema == nil && sc.cacheFile != "" {
+		if cached, err := readSchemaCacheFile(sc.cacheFile); err == nil {
+			if sc.ttl <= 0 || time.Since(cached.FetchedAt) < sc.ttl {
+				sc.schema = cached.Schema
+				sc.fetchedAt = cached.FetchedAt
+				return sc.schema, nil
+			}
+		}
+	}
+
+	data, err := sc.client.ExecRaw(ctx, introspectionQuery, nil)
+	if err != nil {
+		return nil, fmt.Errorf("schema cache: introspection query failed: %w", err)
+	}
+
+	var wrapper struct {
+		Schema introspectionSchema `json:"__schema"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, fmt.Errorf("schema cache: decoding introspection result: %w", err)
+	}
+
+	sc.schema = &wrapper.Schema
+	sc.fetchedAt = time.Now()
+	if sc.cacheFile != "" {
+		_ = writeSchemaCacheFile(sc.cacheFile, sc.schema, sc.fetchedAt)
+	}
+	return sc.schema, nil
+}
+
+func readSchemaCacheFile(path string) (*schemaCacheFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f schemaCacheFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func writeSchemaCacheFile(path string, schema *introspectionSchema, fetchedAt time.Time) error {
+	b, err := json.Marshal(schemaCacheFile{FetchedAt: fetchedAt, Schema: schema})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// WithValidation returns a copy of the client that runs Validate against
+// schemaCache before every Query/Mutate, returning its error instead of
+// sending the request when validation fails.
+func (c *Client) WithValidation(schemaCache *SchemaCache) *Client {
+	return &Client{
+		url:             c.url,
+		httpClient:      c.httpClient,
+		requestModifier: c.requestModifier,
+		debug:           c.debug,
+		retryPolicy:     c.retryPolicy,
+		middlewares:     c.middlewares,
+		httpMethod:      c.httpMethod,
+		maxURLLength:    c.maxURLLength,
+		autoBatch:       c.autoBatch,
+		apqCache:        c.apqCache,
+		validation:      schemaCache,
+	}
+}
+
+// Validate checks query's struct-tag-derived field selections, and any
+// OperationDirective options, against schemaCache's introspected schema:
+// that every selected field exists on its parent type; that every argument
+// is declared, required arguments aren't omitted, and a `<Type>` hint on an
+// argument's variable reference (e.g. `id: $id<ID!>`) matches the schema;
+// that a variable's value, when known and the argument is an enum, is one
+// of the enum's declared values; and that every OperationDirective
+// name+args matches a declared directive valid at this operation's
+// location (or one registered via RegisterDirective). It validates query
+// against the schema's query root; use ValidateMutation for a mutation
+// struct. It does not require WithValidation; call it directly to
+// validate offline (e.g. in a test) without enabling the always-on mode.
+func (c *Client) Validate(ctx context.Context, query any, variables map[string]any, options ...Option) error {
+	return c.validateAgainstRoot(ctx, queryOperation, query, variables, options)
+}
+
+// ValidateMutation is Validate for a mutation struct, checking it against
+// the schema's mutation root instead of its query root.
+func (c *Client) ValidateMutation(ctx context.Context, mutation any, variables map[string]any, options ...Option) error {
+	return c.validateAgainstRoot(ctx, mutationOperation, mutation, variables, options)
+}
+
+func (c *Client) validateAgainstRoot(ctx context.Context, op operationType, v any, variables map[string]any, options []Option) error {
+	schemaCache := c.validation
+	if schemaCache == nil {
+		return fmt.Errorf("validate: client has no SchemaCache; pass one via WithValidation or call schemaCache.Get yourself")
+	}
+	schema, err := schemaCache.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	rootRef := schema.QueryType
+	if op == mutationOperation {
+		rootRef = schema.MutationType
+	}
+	var rootTypeName string
+	if rootRef != nil {
+		rootTypeName = rootRef.Name
+	}
+	rootType := schema.typeByName(rootTypeName)
+	if rootType == nil {
+		return fmt.Errorf("validate: schema has no root type %q", rootTypeName)
+	}
+
+	if err := validateStruct(rootType, schema, v, variables); err != nil {
+		return err
+	}
+
+	directiveLocation := "QUERY"
+	if op == mutationOperation {
+		directiveLocation = "MUTATION"
+	}
+
+	for _, opt := range options {
+		od, ok := opt.(operationDirective)
+		if !ok {
+			continue
+		}
+		dir := schema.directiveByName(od.name)
+		if dir == nil {
+			if _, registered := lookupDirectiveSchema(od.name); !registered {
+				return fmt.Errorf("validate: unknown directive %q", od.name)
+			}
+			continue // custom directives registered via RegisterDirective carry no location info to check
+		}
+		if !containsString(dir.Locations, directiveLocation) {
+			return fmt.Errorf("validate: directive %q is not valid on %s (declared locations: %v)", od.name, directiveLocation, dir.Locations)
+		}
+		for _, a := range od.args {
+			found := false
+			for _, da := range dir.Args {
+				if da.Name == a.Name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("validate: directive %q has no argument %q", od.name, a.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validateStruct recursively checks v's exported fields (and any nested
+// struct/slice-of-struct fields) against parentType's introspected fields.
+// variables backs the enum-value check: when an argument's value is a
+// variable reference, its value (if supplied) is looked up here and
+// checked against the argument type's declared enum values.
+func validateStruct(parentType *introspectionNamedType, schema *introspectionSchema, v any, variables map[string]any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+
+		fieldName, args := parseGraphQLFieldTag(sf)
+		gqlField := parentType.fieldByName(fieldName)
+		if gqlField == nil {
+			return fmt.Errorf("validate: field %q does not exist on type %q", fieldName, parentType.Name)
+		}
+
+		for _, arg := range args {
+			declared := gqlField.argByName(arg.Name)
+			if declared == nil {
+				return fmt.Errorf("validate: field %q has no argument %q on type %q", fieldName, arg.Name, parentType.Name)
+			}
+			if arg.TypeHint != "" && arg.TypeHint != declared.Type.String() {
+				return fmt.Errorf("validate: field %q argument %q is typed %q in the struct tag but %q in the schema",
+					fieldName, arg.Name, arg.TypeHint, declared.Type.String())
+			}
+			if arg.VarName != "" {
+				if enumType := schema.typeByName(declared.Type.baseName()); enumType != nil && enumType.Kind == "ENUM" {
+					if val, ok := variables[arg.VarName]; ok {
+						if s, ok := val.(string); ok && !enumType.hasEnumValue(s) {
+							return fmt.Errorf("validate: field %q argument %q got %q, not a valid value of enum %q",
+								fieldName, arg.Name, s, enumType.Name)
+						}
+					}
+				}
+			}
+		}
+		for _, declared := range gqlField.Args {
+			if declared.required() && !hasArgNamed(args, declared.Name) {
+				return fmt.Errorf("validate: field %q is missing required argument %q on type %q", fieldName, declared.Name, parentType.Name)
+			}
+		}
+
+		fv := rv.Field(i)
+		nested := fv
+		for nested.Kind() == reflect.Ptr || nested.Kind() == reflect.Slice {
+			if nested.Kind() == reflect.Ptr {
+				if nested.IsNil() {
+					break
+				}
+				nested = nested.Elem()
+			} else {
+				// A slice's element type may itself be a pointer (e.g.
+				// []*Foo, the shape a generated GraphQL input struct
+				// actually produces) - reflect.New(elemType).Elem() on
+				// that pointer type would yield a nil *Foo Value, not a
+				// Foo, so unwrap every pointer layer on the type first.
+				elemType := nested.Type().Elem()
+				for elemType.Kind() == reflect.Ptr {
+					elemType = elemType.Elem()
+				}
+				nested = reflect.New(elemType).Elem()
+			}
+		}
+		if nested.Kind() != reflect.Struct {
+			continue
+		}
+
+		childType := schema.typeByName(gqlField.Type.baseName())
+		if childType == nil {
+			continue // scalar/enum leaf, or a type introspection didn't resolve; nothing more to check
+		}
+		if err := validateStruct(childType, schema, nested.Addr().Interface(), variables); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// graphqlArg is one `arg: $var` pair parsed out of a field's graphql struct
+// tag. VarName is the referenced operation variable ("var" in "$var"),
+// empty when the value is a literal rather than a variable reference.
+// TypeHint is the optional `<Type>` suffix on the variable reference (e.g.
+// `id: $id<ID!>`), following the convention struct-tag clients like goql
+// use to let a validator check the argument's type without a live
+// variables map; it is empty when the tag didn't specify one.
+type graphqlArg struct {
+	Name     string
+	VarName  string
+	TypeHint string
+}
+
+func hasArgNamed(args []graphqlArg, name string) bool {
+	for _, a := range args {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+var argTypeHintRe = regexp.MustCompile(`<([^>]+)>\s*$`)
+
+// parseGraphQLFieldTag splits this library's `graphql:"name(arg: $var, ...)"`
+// struct tag into the selected field name and its arguments. Fields without
+// a tag fall back to their lowerCamel'd Go name with no arguments. An
+// argument's variable reference may carry a `<Type>` hint (`arg: $var<Int!>`)
+// recording the GraphQL type Validate should check it against.
+func parseGraphQLFieldTag(sf reflect.StructField) (name string, args []graphqlArg) {
+	tag := sf.Tag.Get("graphql")
+	if tag == "" {
+		return lowerFirst(sf.Name), nil
+	}
+
+	name = tag
+	if paren := strings.IndexByte(tag, '('); paren >= 0 {
+		name = tag[:paren]
+		argsPart := strings.TrimSuffix(tag[paren+1:], ")")
+		for _, pair := range strings.Split(argsPart, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			colon := strings.IndexByte(pair, ':')
+			if colon < 0 {
+				continue
+			}
+			argName := strings.TrimSpace(pair[:colon])
+			value := strings.TrimSpace(pair[colon+1:])
+			var typeHint string
+			if m := argTypeHintRe.FindStringSubmatch(value); m != nil {
+				typeHint = m[1]
+				value = strings.TrimSpace(value[:len(value)-len(m[0])])
+			}
+			var varName string
+			if strings.HasPrefix(value, "$") {
+				varName = strings.TrimPrefix(value, "$")
+			}
+			args = append(args, graphqlArg{Name: argName, VarName: varName, TypeHint: typeHint})
+		}
+	}
+	name = strings.TrimSpace(strings.SplitN(name, ":", 2)[0])
+	return name, args
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,723 @@
+package graphql
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hasura/go-graphql-client/pkg/jsonutil"
+)
+
+// graphqlTransportWS is the Sec-WebSocket-Protocol negotiated for GraphQL
+// subscriptions, per https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md.
+const graphqlTransportWS = "graphql-transport-ws"
+
+// operationMessageType is the "type" field of a graphql-transport-ws frame.
+type operationMessageType string
+
+const (
+	gqlConnectionInit operationMessageType = "connection_init"
+	gqlConnectionAck  operationMessageType = "connection_ack"
+	gqlSubscribe      operationMessageType = "subscribe"
+	gqlNext           operationMessageType = "next"
+	gqlError          operationMessageType = "error"
+	gqlComplete       operationMessageType = "complete"
+	gqlPing           operationMessageType = "ping"
+	gqlPong           operationMessageType = "pong"
+)
+
+// operationMessage is the envelope every graphql-transport-ws frame is sent
+// and received as.
+type operationMessage struct {
+	ID      string               `json:"id,omitempty"`
+	Type    operationMessageType `json:"type"`
+	Payload json.RawMessage      `json:"payload,omitempty"`
+}
+
+// subscribePayload is the Payload of a "subscribe" operationMessage.
+type subscribePayload struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+}
+
+// subscriptionResponse is the Payload of a "next" operationMessage.
+type subscriptionResponse struct {
+	Data       json.RawMessage `json:"data,omitempty"`
+	Errors     Errors          `json:"errors,omitempty"`
+	Extensions json.RawMessage `json:"extensions,omitempty"`
+}
+
+// Conn abstracts a single full-duplex JSON message stream, so the
+// SubscriptionClient doesn't have to depend on a concrete websocket
+// implementation. The default Dialer's Conn speaks RFC 6455 text frames.
+type Conn interface {
+	WriteJSON(v any) error
+	ReadJSON(v any) error
+	Close() error
+}
+
+// Dialer opens a Conn for the given sub-protocols. It is the extension
+// point for swapping in a different websocket implementation (or a fake
+// one in tests) without forking SubscriptionClient.
+type Dialer interface {
+	Dial(ctx context.Context, url string, protocols []string, header http.Header) (Conn, error)
+}
+
+// Subscription is a handle to one active graphql-transport-ws subscription
+// multiplexed over its SubscriptionClient's socket. Next delivers each
+// "next" message decoded with the same struct-tag decoder Query uses,
+// allocated fresh per message with the same concrete type as the value
+// passed to Subscribe. Err receives at most one terminal error (a "error"
+// message, or the subscription being dropped by a failed reconnect) before
+// Next is closed.
+type Subscription struct {
+	ID   string
+	Next chan any
+	Err  chan error
+
+	sc        *SubscriptionClient
+	elemType  reflect.Type
+	extension any
+	payload   subscribePayload
+
+	closeOnce sync.Once
+}
+
+// Close unsubscribes and stops delivering messages. It is safe to call more
+// than once.
+func (s *Subscription) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.sc.unsubscribe(s.ID, true)
+		close(s.Next)
+		close(s.Err)
+	})
+	return err
+}
+
+// SubscriptionClient speaks graphql-transport-ws over a single Dialer
+// connection, multiplexing every concurrent Subscribe call over it with
+// unique operation IDs, and reconnecting with backoff when the socket
+// drops.
+type SubscriptionClient struct {
+	url              string
+	dialer           Dialer
+	protocols        []string
+	header           http.Header
+	connectionParams map[string]any
+	retryPolicy      RetryPolicy
+
+	mu       sync.Mutex
+	conn     Conn
+	subs     map[string]*Subscription
+	nextID   uint64
+	closed   bool
+	ready    chan struct{} // closed/replaced each time conn becomes usable
+}
+
+// NewSubscriptionClient creates a client targeting the given ws(s):// URL.
+// Call Run in its own goroutine before Subscribe.
+func NewSubscriptionClient(url string) *SubscriptionClient {
+	return &SubscriptionClient{
+		url:       url,
+		dialer:    defaultDialer{},
+		protocols: []string{graphqlTransportWS},
+		subs:      map[string]*Subscription{},
+		ready:     make(chan struct{}),
+		retryPolicy: RetryPolicy{
+			MaxRetries: -1, // reconnect indefinitely until Close
+			BaseDelay:  1 * time.Second,
+			MaxDelay:   30 * time.Second,
+		},
+	}
+}
+
+// WithWebSocketDialer overrides the Dialer used to open the connection.
+func (sc *SubscriptionClient) WithWebSocketDialer(d Dialer) *SubscriptionClient {
+	sc.dialer = d
+	return sc
+}
+
+// WithConnectionParams sets the payload sent with connection_init, e.g. an
+// auth token: {"Authorization": "Bearer ..."}.
+func (sc *SubscriptionClient) WithConnectionParams(params map[string]any) *SubscriptionClient {
+	sc.connectionParams = params
+	return sc
+}
+
+// WithRequestHeader sets extra headers sent with the websocket handshake.
+func (sc *SubscriptionClient) WithRequestHeader(header http.Header) *SubscriptionClient {
+	sc.header = header
+	return sc
+}
+
+// WithRetryPolicy overrides the reconnect backoff. A negative MaxRetries
+// means retry indefinitely; this is the default.
+func (sc *SubscriptionClient) WithRetryPolicy(policy RetryPolicy) *SubscriptionClient {
+	sc.retryPolicy = policy
+	return sc
+}
+
+// Run connects and pumps incoming messages to their Subscriptions until ctx
+// is canceled or Close is called, reconnecting with backoff in between. It
+// blocks, so callers start it with `go sc.Run(ctx)`.
+func (sc *SubscriptionClient) Run(ctx context.Context) error {
+	for attempt := 0; ; attempt++ {
+		sc.mu.Lock()
+		if sc.closed {
+			sc.mu.Unlock()
+			return nil
+		}
+		sc.mu.Unlock()
+
+		err := sc.connectAndPump(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		sc.mu.Lock()
+		closed := sc.closed
+		sc.mu.Unlock()
+		if closed {
+			return nil
+		}
+
+		if sc.retryPolicy.MaxRetries >= 0 && attempt >= sc.retryPolicy.MaxRetries {
+			return fmt.Errorf("subscription client: giving up reconnecting: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoffWithJitter(sc.retryPolicy, attempt)):
+		}
+	}
+}
+
+// connectAndPump dials, performs connection_init/ack, resubscribes any
+// subscription registered from a previous connection, then reads frames
+// until the socket fails or Close is called.
+func (sc *SubscriptionClient) connectAndPump(ctx context.Context) error {
+	conn, err := sc.dialer.Dial(ctx, sc.url, sc.protocols, sc.header)
+	if err != nil {
+		return fmt.Errorf("subscription client: dial failed: %w", err)
+	}
+
+	initPayload, err := json.Marshal(sc.connectionParams)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("subscription client: encoding connection_init payload: %w", err)
+	}
+	if err := conn.WriteJSON(operationMessage{Type: gqlConnectionInit, Payload: initPayload}); err != nil {
+		conn.Close()
+		return fmt.Errorf("subscription client: connection_init failed: %w", err)
+	}
+
+	var ack operationMessage
+	if err := conn.ReadJSON(&ack); err != nil {
+		conn.Close()
+		return fmt.Errorf("subscription client: waiting for connection_ack: %w", err)
+	}
+	if ack.Type != gqlConnectionAck {
+		conn.Close()
+		return fmt.Errorf("subscription client: expected connection_ack, got %q", ack.Type)
+	}
+
+	sc.mu.Lock()
+	sc.conn = conn
+	close(sc.ready)
+	sc.ready = make(chan struct{})
+	subs := make(map[string]*Subscription, len(sc.subs))
+	for id, s := range sc.subs {
+		subs[id] = s
+	}
+	sc.mu.Unlock()
+
+	defer func() {
+		sc.mu.Lock()
+		if sc.conn == conn {
+			sc.conn = nil
+		}
+		sc.mu.Unlock()
+		conn.Close()
+	}()
+
+	for id, s := range subs {
+		if err := sc.sendSubscribe(id, s); err != nil {
+			return err
+		}
+	}
+
+	for {
+		var msg operationMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return fmt.Errorf("subscription client: read failed: %w", err)
+		}
+		sc.dispatch(msg)
+	}
+}
+
+func (sc *SubscriptionClient) dispatch(msg operationMessage) {
+	if msg.Type == gqlPing {
+		sc.mu.Lock()
+		conn := sc.conn
+		sc.mu.Unlock()
+		if conn != nil {
+			_ = conn.WriteJSON(operationMessage{Type: gqlPong})
+		}
+		return
+	}
+	if msg.Type == gqlPong {
+		return
+	}
+
+	sc.mu.Lock()
+	s := sc.subs[msg.ID]
+	sc.mu.Unlock()
+	if s == nil {
+		return
+	}
+
+	switch msg.Type {
+	case gqlNext:
+		var resp subscriptionResponse
+		if err := json.Unmarshal(msg.Payload, &resp); err != nil {
+			sc.deliverErr(s, fmt.Errorf("subscription client: decoding next payload: %w", err))
+			return
+		}
+		if len(resp.Errors) > 0 {
+			sc.deliverErr(s, resp.Errors)
+			return
+		}
+		if len(resp.Data) > 0 {
+			v := reflect.New(s.elemType).Interface()
+			if err := jsonutil.UnmarshalGraphQL(resp.Data, v); err != nil {
+				sc.deliverErr(s, fmt.Errorf("subscription client: decoding data: %w", err))
+				return
+			}
+			if s.extension != nil && len(resp.Extensions) > 0 {
+				_ = json.Unmarshal(resp.Extensions, s.extension)
+			}
+			sc.deliverNext(s, v)
+		}
+	case gqlError:
+		var errs Errors
+		_ = json.Unmarshal(msg.Payload, &errs)
+		sc.deliverErr(s, errs)
+	case gqlComplete:
+		_ = sc.unsubscribe(msg.ID, false)
+		s.closeOnce.Do(func() {
+			close(s.Next)
+			close(s.Err)
+		})
+	}
+}
+
+func (sc *SubscriptionClient) deliverErr(s *Subscription, err error) {
+	select {
+	case s.Err <- err:
+	default:
+	}
+}
+
+// deliverNext sends v to s.Next without ever blocking the single read loop
+// connectAndPump calls dispatch from. Next has capacity 1; if the consumer
+// hasn't drained the previous value yet, that stale value is dropped in
+// favor of v rather than blocking, so one slow consumer can't stall
+// delivery to every other subscription multiplexed over the same socket.
+func (sc *SubscriptionClient) deliverNext(s *Subscription, v any) {
+	for {
+		select {
+		case s.Next <- v:
+			return
+		default:
+		}
+		select {
+		case <-s.Next:
+		default:
+		}
+	}
+}
+
+// Subscribe starts a new subscription multiplexed over sc's connection,
+// registering it immediately and sending the "subscribe" frame once
+// connected (blocking on that, but not on any server response, so callers
+// don't need Run to have connected yet).
+func (sc *SubscriptionClient) Subscribe(ctx context.Context, subscription any, variables map[string]any, options ...Option) (*Subscription, error) {
+	query, optionsOutput, err := constructSubscription(subscription, variables, options...)
+	if err != nil {
+		return nil, Errors{newError(ErrGraphQLEncode, err)}
+	}
+
+	elemType := reflect.TypeOf(subscription)
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	sc.mu.Lock()
+	if sc.closed {
+		sc.mu.Unlock()
+		return nil, fmt.Errorf("subscription client: closed")
+	}
+	sc.nextID++
+	id := fmt.Sprintf("%d", sc.nextID)
+	s := &Subscription{
+		ID:        id,
+		Next:      make(chan any, 1),
+		Err:       make(chan error, 1),
+		sc:        sc,
+		elemType:  elemType,
+		extension: optionsOutput.extensions,
+	}
+	s.payload = subscribePayload{
+		Query:         query,
+		Variables:     variables,
+		OperationName: optionsOutput.operationName,
+	}
+	sc.subs[id] = s
+	conn := sc.conn
+	sc.mu.Unlock()
+
+	if conn != nil {
+		if err := sc.sendSubscribe(id, s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (sc *SubscriptionClient) sendSubscribe(id string, s *Subscription) error {
+	payload, err := json.Marshal(s.payload)
+	if err != nil {
+		return fmt.Errorf("subscription client: encoding subscribe payload: %w", err)
+	}
+
+	sc.mu.Lock()
+	conn := sc.conn
+	sc.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	return conn.WriteJSON(operationMessage{ID: id, Type: gqlSubscribe, Payload: payload})
+}
+
+// unsubscribe removes the subscription's bookkeeping and, if notify is
+// true (a caller-initiated Close rather than a server-initiated complete),
+// sends a "complete" frame so the server stops pushing for it.
+func (sc *SubscriptionClient) unsubscribe(id string, notify bool) error {
+	sc.mu.Lock()
+	s, ok := sc.subs[id]
+	conn := sc.conn
+	delete(sc.subs, id)
+	sc.mu.Unlock()
+
+	if !ok || !notify || conn == nil {
+		return nil
+	}
+	_ = s
+	return conn.WriteJSON(operationMessage{ID: id, Type: gqlComplete})
+}
+
+// Close stops Run's reconnect loop and tears down every live subscription.
+func (sc *SubscriptionClient) Close() error {
+	sc.mu.Lock()
+	if sc.closed {
+		sc.mu.Unlock()
+		return nil
+	}
+	sc.closed = true
+	conn := sc.conn
+	sc.mu.Unlock()
+
+	if conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
+
+// defaultDialer implements Dialer with a minimal RFC 6455 websocket client,
+// so SubscriptionClient has no third-party dependency by default.
+type defaultDialer struct{}
+
+func (defaultDialer) Dial(ctx context.Context, rawURL string, protocols []string, header http.Header) (Conn, error) {
+	return dialWebSocket(ctx, rawURL, protocols, header)
+}
+
+type websocketConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	writeMu sync.Mutex
+}
+
+func dialWebSocket(ctx context.Context, rawURL string, protocols []string, header http.Header) (*websocketConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var network string
+	var tlsConf *tls.Config
+	switch u.Scheme {
+	case "ws":
+		network = "tcp"
+	case "wss":
+		network = "tcp"
+		tlsConf = &tls.Config{ServerName: u.Hostname()}
+	default:
+		return nil, fmt.Errorf("unsupported websocket scheme %q", u.Scheme)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		if u.Scheme == "wss" {
+			addr = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			addr = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	var d net.Dialer
+	rawConn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConf != nil {
+		rawConn = tls.Client(rawConn, tlsConf)
+	}
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		rawConn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	requestPath := u.RequestURI()
+	var req strings.Builder
+	fmt.Fprintf(&req, "GET %s HTTP/1.1\r\n", requestPath)
+	fmt.Fprintf(&req, "Host: %s\r\n", u.Host)
+	req.WriteString("Upgrade: websocket\r\n")
+	req.WriteString("Connection: Upgrade\r\n")
+	fmt.Fprintf(&req, "Sec-WebSocket-Key: %s\r\n", key)
+	req.WriteString("Sec-WebSocket-Version: 13\r\n")
+	if len(protocols) > 0 {
+		fmt.Fprintf(&req, "Sec-WebSocket-Protocol: %s\r\n", strings.Join(protocols, ", "))
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			fmt.Fprintf(&req, "%s: %s\r\n", k, v)
+		}
+	}
+	req.WriteString("\r\n")
+
+	if _, err := rawConn.Write([]byte(req.String())); err != nil {
+		rawConn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(rawConn)
+	tp := textproto.NewReader(br)
+	statusLine, err := tp.ReadLine()
+	if err != nil {
+		rawConn.Close()
+		return nil, err
+	}
+	if !strings.Contains(statusLine, "101") {
+		rawConn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: %s", statusLine)
+	}
+	respHeader, err := tp.ReadMIMEHeader()
+	if err != nil {
+		rawConn.Close()
+		return nil, err
+	}
+
+	expectedAccept := computeAcceptKey(key)
+	if respHeader.Get("Sec-Websocket-Accept") != expectedAccept {
+		rawConn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: bad Sec-WebSocket-Accept")
+	}
+
+	return &websocketConn{conn: rawConn, br: br}, nil
+}
+
+func computeAcceptKey(key string) string {
+	const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+func (c *websocketConn) WriteJSON(v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(wsOpText, payload)
+}
+
+func (c *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	var header []byte
+	header = append(header, 0x80|opcode) // FIN + opcode
+
+	maskKey := make([]byte, 4)
+	if _, err := rand.Read(maskKey); err != nil {
+		return err
+	}
+
+	n := len(payload)
+	switch {
+	case n <= 125:
+		header = append(header, 0x80|byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 0x80|126, byte(n>>8), byte(n))
+	default:
+		ext := make([]byte, 8)
+		for i := 7; i >= 0; i-- {
+			ext[i] = byte(n)
+			n >>= 8
+		}
+		header = append(header, 0x80|127)
+		header = append(header, ext...)
+		n = len(payload)
+	}
+	header = append(header, maskKey...)
+
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ maskKey[i%4]
+	}
+
+	if _, err := c.conn.Write(header); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(masked)
+	return err
+}
+
+// ReadJSON reads the next complete text message (following continuation
+// frames and auto-handling ping/close control frames) and decodes it as v.
+func (c *websocketConn) ReadJSON(v any) error {
+	for {
+		opcode, payload, err := c.readMessage()
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			return io.EOF
+		case wsOpText, wsOpBinary:
+			return json.Unmarshal(payload, v)
+		}
+	}
+}
+
+func (c *websocketConn) readMessage() (opcode byte, payload []byte, err error) {
+	var fragmented []byte
+	for {
+		b0, err := c.br.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		fin := b0&0x80 != 0
+		op := b0 & 0x0F
+
+		b1, err := c.br.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		masked := b1&0x80 != 0
+		length := uint64(b1 & 0x7F)
+
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+				return 0, nil, err
+			}
+			length = uint64(ext[0])<<8 | uint64(ext[1])
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+				return 0, nil, err
+			}
+			length = 0
+			for _, bb := range ext {
+				length = length<<8 | uint64(bb)
+			}
+		}
+
+		var maskKey [4]byte
+		if masked {
+			if _, err := io.ReadFull(c.br, maskKey[:]); err != nil {
+				return 0, nil, err
+			}
+		}
+
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(c.br, frame); err != nil {
+			return 0, nil, err
+		}
+		if masked {
+			for i := range frame {
+				frame[i] ^= maskKey[i%4]
+			}
+		}
+
+		if op == wsOpContinuation {
+			fragmented = append(fragmented, frame...)
+		} else {
+			fragmented = append([]byte(nil), frame...)
+		}
+
+		if fin {
+			if op == wsOpContinuation {
+				// opcode of a continuation's first frame isn't tracked here;
+				// graphql-transport-ws never fragments, so this path is only
+				// hit by well-behaved servers closing out a single frame.
+				return wsOpText, fragmented, nil
+			}
+			return op, fragmented, nil
+		}
+	}
+}
+
+func (c *websocketConn) Close() error {
+	_ = c.writeFrame(wsOpClose, nil)
+	return c.conn.Close()
+}
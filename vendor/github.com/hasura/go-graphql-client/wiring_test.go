@@ -0,0 +1,122 @@
+package graphql
+
+// This file lives under vendor/, so `go test ./...` from the module root
+// never runs it - go list ./... (and therefore go test/go vet ./...)
+// categorically excludes anything under a vendor directory. These wiring
+// fixes have no coverage anywhere else in the tree, so until they're
+// upstreamed, run them explicitly:
+//
+//	go test -mod=vendor github.com/hasura/go-graphql-client
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestSpliceOperationDirectives covers the chunk1-6 fix: RenderDirectives'
+// output actually lands in the query text buildQueryAndOptions returns, and
+// every variable it references gets auto-declared in the operation's
+// variable-definition list, even when nothing in the selection set itself
+// references that variable.
+func TestSpliceOperationDirectives(t *testing.T) {
+	directives, referencedVars := RenderDirectives([]Option{IncludeIf("show")})
+	if directives == "" {
+		t.Fatalf("RenderDirectives returned no directive text for IncludeIf")
+	}
+	if want := []string{"show"}; !reflect.DeepEqual(referencedVars, want) {
+		t.Fatalf("referencedVars = %v, want %v", referencedVars, want)
+	}
+
+	named, err := spliceOperationDirectives(queryOperation, `query GetThing($id: ID!) { thing(id: $id) { name } }`, directives, referencedVars)
+	if err != nil {
+		t.Fatalf("spliceOperationDirectives: %v", err)
+	}
+	if want := `query GetThing($id: ID!, $show: Boolean) @include(if: $show) { thing(id: $id) { name } }`; named != want {
+		t.Fatalf("named query = %q, want %q", named, want)
+	}
+
+	anonymous, err := spliceOperationDirectives(mutationOperation, `{ thing { name } }`, directives, referencedVars)
+	if err != nil {
+		t.Fatalf("spliceOperationDirectives: %v", err)
+	}
+	if want := `mutation($show: Boolean) @include(if: $show) { thing { name } }`; anonymous != want {
+		t.Fatalf("anonymous mutation = %q, want %q", anonymous, want)
+	}
+
+	alreadyDeclared, err := spliceOperationDirectives(queryOperation, `query GetThing($show: Boolean) { thing { name } }`, directives, referencedVars)
+	if err != nil {
+		t.Fatalf("spliceOperationDirectives: %v", err)
+	}
+	if want := `query GetThing($show: Boolean) @include(if: $show) { thing { name } }`; alreadyDeclared != want {
+		t.Fatalf("already-declared query = %q, want %q (must not re-declare $show)", alreadyDeclared, want)
+	}
+
+	if _, err := spliceOperationDirectives(queryOperation, "no selection set here", directives, referencedVars); err == nil {
+		t.Fatalf("expected an error for a query with no selection set")
+	}
+}
+
+// TestHasUploadsConcreteSlice covers the chunk1-3 fix: a concretely-typed
+// []*Upload (as opposed to a []any holding *Upload values) must still be
+// detected, since that's the shape a generated GraphQL input struct
+// actually produces.
+func TestHasUploadsConcreteSlice(t *testing.T) {
+	variables := map[string]any{
+		"files": []*Upload{{Filename: "a.txt"}, {Filename: "b.txt"}},
+	}
+	if !hasUploads(variables) {
+		t.Fatalf("hasUploads did not find *Upload nested in a concrete []*Upload")
+	}
+
+	cleaned, refs := collectUploads(variables)
+	if len(refs) != 2 {
+		t.Fatalf("collectUploads found %d uploads, want 2", len(refs))
+	}
+	files, ok := cleaned["files"].([]*Upload)
+	if !ok {
+		t.Fatalf("cleaned[\"files\"] has type %T, want []*Upload", cleaned["files"])
+	}
+	for i, f := range files {
+		if f != nil {
+			t.Fatalf("cleaned files[%d] = %v, want nil", i, f)
+		}
+	}
+}
+
+// TestDispatchCompleteClosesChannels covers the chunk1-2 fix: a
+// server-initiated "complete" frame must close Next/Err, symmetric with
+// client-initiated Close, so a consumer range-looping over Next isn't left
+// blocked forever with no indication the subscription ended server-side.
+func TestDispatchCompleteClosesChannels(t *testing.T) {
+	s := &Subscription{ID: "1", Next: make(chan any, 1), Err: make(chan error, 1)}
+	sc := &SubscriptionClient{subs: map[string]*Subscription{"1": s}}
+
+	sc.dispatch(operationMessage{ID: "1", Type: gqlComplete})
+
+	if _, ok := <-s.Next; ok {
+		t.Fatalf("Next was not closed after a server-initiated complete")
+	}
+	if _, ok := <-s.Err; ok {
+		t.Fatalf("Err was not closed after a server-initiated complete")
+	}
+}
+
+// TestDeliverNextDropsStaleValue covers the chunk1-2 fix: delivering to a
+// full Next channel must not block, even when nothing is draining it.
+func TestDeliverNextDropsStaleValue(t *testing.T) {
+	s := &Subscription{Next: make(chan any, 1)}
+	sc := &SubscriptionClient{}
+
+	done := make(chan struct{})
+	go func() {
+		sc.deliverNext(s, "first")
+		sc.deliverNext(s, "second") // Next is still full of "first"; must not block
+		close(done)
+	}()
+
+	<-done
+
+	if got := <-s.Next; got != "second" {
+		t.Fatalf("Next delivered %v, want the newer value %q", got, "second")
+	}
+}